@@ -0,0 +1,84 @@
+package redsys
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Limits of the amount of a Bizum operation in cents of euro.
+const (
+	BizumMinAmount = 50
+	BizumMaxAmount = 100000
+)
+
+// ProcessedPayMethodBizum is the processed pay method reported by the bank in the Bizum operations.
+const ProcessedPayMethodBizum = 68
+
+var reBizumMobileNumber = regexp.MustCompile(`^\+34[0-9]{9}$`)
+
+// checkBizum validates the currency and the amount of a Bizum operation before signing it.
+func checkBizum(currency Currency, amount, min int32) error {
+	if currency != CurrencyEuros {
+		return fmt.Errorf("bizum only supports euros, got currency %d", currency)
+	}
+	if amount < min || amount > BizumMaxAmount {
+		return fmt.Errorf("bizum amount %d out of the limits from %d to %d", amount, min, BizumMaxAmount)
+	}
+	return nil
+}
+
+func (session Session) checkBizum(currency Currency) error {
+	if session.PaymentMethod != PaymentMethodBizum {
+		if session.BizumMobileNumber != "" {
+			return fmt.Errorf("bizum mobile number requires the bizum payment method")
+		}
+		return nil
+	}
+	if session.BizumMobileNumber != "" && !reBizumMobileNumber.MatchString(session.BizumMobileNumber) {
+		return fmt.Errorf("invalid bizum mobile number %q", session.BizumMobileNumber)
+	}
+	return checkBizum(currency, session.Amount, BizumMinAmount)
+}
+
+// IsBizum returns true if the bank processed the operation with Bizum.
+func (params Params) IsBizum() bool {
+	return params.ProcessedPayMethod == ProcessedPayMethodBizum
+}
+
+// RefundBizum returns to the customer the amount of a previously approved Bizum order. It works like Refund but
+// checks the Bizum limits before sending the refund.
+func (client *Client) RefundBizum(ctx context.Context, merchant Merchant, order string, amount int32, history ...Operation) (Operation, error) {
+	currency, err := merchant.currency()
+	if err != nil {
+		return Operation{}, err
+	}
+	if err := checkBizum(currency, amount, 0); err != nil {
+		return Operation{}, err
+	}
+	return client.refund(ctx, merchant, Request{
+		TransactionType: TransactionTypeRefund,
+		Order:           order,
+		Amount:          amount,
+		PaymentMethod:   PaymentMethodBizum,
+	}, history)
+}
+
+// Ds_Response codes with a different meaning in the Bizum operations.
+var bizumResponses = map[int64]responseEntry{
+	121:  {CategoryDenied, false, "Importe superior al límite de Bizum del cliente", "Amount over the Bizum limit of the customer"},
+	180:  {CategoryDenied, false, "El teléfono no está dado de alta en Bizum", "The phone is not registered in Bizum"},
+	184:  {CategoryAuthentication, true, "El cliente no ha confirmado la operación en la aplicación de su banco", "The customer did not confirm the operation in the bank app"},
+	190:  {CategoryDenied, false, "Operación Bizum denegada por la entidad del cliente", "Bizum operation denied by the bank of the customer"},
+	9142: {CategoryCancelled, true, "Tiempo excedido para confirmar la operación Bizum", "Time exceeded to confirm the Bizum operation"},
+	9999: {CategoryPending, false, "Operación Bizum pendiente de confirmación en la aplicación del cliente", "Bizum operation waiting for the confirmation in the bank app"},
+}
+
+// LookupBizumResponse returns the description of a Ds_Response code of a Bizum operation. Codes without a specific
+// Bizum meaning are looked up in the general catalog.
+func LookupBizumResponse(code int64) (ResponseInfo, bool) {
+	if entry, ok := bizumResponses[code]; ok {
+		return entry.info(fmt.Sprintf("%04d", code)), true
+	}
+	return LookupResponse(code)
+}
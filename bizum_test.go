@@ -0,0 +1,137 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func bizumSession() Session {
+	return Session{
+		Order:             "00011234abcd",
+		Amount:            1000,
+		PaymentMethod:     PaymentMethodBizum,
+		BizumMobileNumber: "+34700000000",
+	}
+}
+
+func TestSignBizum(t *testing.T) {
+	signed, err := Sign(context.Background(), testMerchant(), bizumSession())
+	require.NoError(t, err)
+
+	decoded, err := base64.URLEncoding.DecodeString(signed.Params)
+	require.NoError(t, err)
+	params := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(decoded, &params))
+
+	require.Equal(t, params["Ds_Merchant_PayMethods"], "z")
+	require.Equal(t, params["Ds_Merchant_Bizum_MobileNumber"], "+34700000000")
+}
+
+func TestSignBizumLimits(t *testing.T) {
+	session := bizumSession()
+	session.Amount = 49
+	_, err := Sign(context.Background(), testMerchant(), session)
+	require.EqualError(t, err, "bizum amount 49 out of the limits from 50 to 100000")
+
+	session.Amount = 100001
+	_, err = Sign(context.Background(), testMerchant(), session)
+	require.EqualError(t, err, "bizum amount 100001 out of the limits from 50 to 100000")
+
+	session.Amount = 100000
+	_, err = Sign(context.Background(), testMerchant(), session)
+	require.NoError(t, err)
+}
+
+func TestSignBizumCurrency(t *testing.T) {
+	merchant := testMerchant()
+	merchant.Currency = CurrencyDollars
+	_, err := Sign(context.Background(), merchant, bizumSession())
+	require.EqualError(t, err, "bizum only supports euros, got currency 840")
+}
+
+func TestSignBizumInvalidMobileNumber(t *testing.T) {
+	session := bizumSession()
+	session.BizumMobileNumber = "700000000"
+	_, err := Sign(context.Background(), testMerchant(), session)
+	require.EqualError(t, err, `invalid bizum mobile number "700000000"`)
+}
+
+func TestSignBizumMobileNumberWithCard(t *testing.T) {
+	session := bizumSession()
+	session.PaymentMethod = PaymentMethodCreditCard
+	_, err := Sign(context.Background(), testMerchant(), session)
+	require.EqualError(t, err, "bizum mobile number requires the bizum payment method")
+}
+
+func TestConfirmBizum(t *testing.T) {
+	tests := []struct {
+		response string
+		status   Status
+		outcome  Status
+	}{
+		{"0000", StatusApproved, StatusApproved},
+		{"0180", StatusCancelled, StatusDenied},
+		{"0184", StatusCancelled, StatusDenied},
+		{"9142", StatusCancelled, StatusCancelled},
+		{"9999", StatusUnknown, StatusPending},
+	}
+	for _, test := range tests {
+		signed := signNotification(t, map[string]string{
+			"Ds_Order":              "00011234abcd",
+			"Ds_Response":           test.response,
+			"Ds_ProcessedPayMethod": "68",
+		})
+		operation, err := Confirm(context.Background(), testSecret, signed)
+		require.NoError(t, err)
+
+		require.True(t, operation.Params.IsBizum())
+		require.EqualValues(t, operation.Params.ProcessedPayMethod, ProcessedPayMethodBizum)
+		require.Equal(t, operation.Status, test.status, test.response)
+		require.Equal(t, operation.Outcome, test.outcome, test.response)
+	}
+}
+
+func TestLookupBizumResponse(t *testing.T) {
+	info, ok := LookupBizumResponse(180)
+	require.True(t, ok)
+	require.Equal(t, info.DescriptionEN, "The phone is not registered in Bizum")
+
+	info, ok = LookupBizumResponse(101)
+	require.True(t, ok)
+	require.Equal(t, info.DescriptionEN, "Expired card")
+}
+
+func TestRefundBizum(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		require.Equal(t, params["Ds_Merchant_TransactionType"], float64(3))
+		require.Equal(t, params["Ds_Merchant_PayMethods"], "z")
+		require.Equal(t, params["Ds_Merchant_Amount"], float64(400))
+		return map[string]interface{}{
+			"Ds_Order":              "00011234abcd",
+			"Ds_Response":           "0900",
+			"Ds_Amount":             "400",
+			"Ds_TransactionType":    "3",
+			"Ds_ProcessedPayMethod": "68",
+		}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operation, err := client.RefundBizum(context.Background(), testMerchant(), "00011234abcd", 400)
+	require.NoError(t, err)
+
+	require.Equal(t, operation.Status, StatusApproved)
+	require.True(t, operation.Params.IsBizum())
+}
+
+func TestRefundBizumLimits(t *testing.T) {
+	client := new(Client)
+	_, err := client.RefundBizum(context.Background(), testMerchant(), "00011234abcd", 100001)
+	require.EqualError(t, err, "bizum amount 100001 out of the limits from 0 to 100000")
+
+	_, err = client.RefundBizum(context.Background(), testMerchant(), "00011234abcd", 0)
+	require.EqualError(t, err, "invalid refund amount 0")
+}
@@ -0,0 +1,321 @@
+package redsys
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ISO 3166-1 numeric codes of each alpha-2 country code.
+var countries = map[string]string{
+	"AD": "020", "AE": "784", "AF": "004", "AG": "028", "AI": "660", "AL": "008", "AM": "051", "AO": "024",
+	"AQ": "010", "AR": "032", "AS": "016", "AT": "040", "AU": "036", "AW": "533", "AX": "248", "AZ": "031",
+	"BA": "070", "BB": "052", "BD": "050", "BE": "056", "BF": "854", "BG": "100", "BH": "048", "BI": "108",
+	"BJ": "204", "BL": "652", "BM": "060", "BN": "096", "BO": "068", "BQ": "535", "BR": "076", "BS": "044",
+	"BT": "064", "BV": "074", "BW": "072", "BY": "112", "BZ": "084", "CA": "124", "CC": "166", "CD": "180",
+	"CF": "140", "CG": "178", "CH": "756", "CI": "384", "CK": "184", "CL": "152", "CM": "120", "CN": "156",
+	"CO": "170", "CR": "188", "CU": "192", "CV": "132", "CW": "531", "CX": "162", "CY": "196", "CZ": "203",
+	"DE": "276", "DJ": "262", "DK": "208", "DM": "212", "DO": "214", "DZ": "012", "EC": "218", "EE": "233",
+	"EG": "818", "EH": "732", "ER": "232", "ES": "724", "ET": "231", "FI": "246", "FJ": "242", "FK": "238",
+	"FM": "583", "FO": "234", "FR": "250", "GA": "266", "GB": "826", "GD": "308", "GE": "268", "GF": "254",
+	"GG": "831", "GH": "288", "GI": "292", "GL": "304", "GM": "270", "GN": "324", "GP": "312", "GQ": "226",
+	"GR": "300", "GS": "239", "GT": "320", "GU": "316", "GW": "624", "GY": "328", "HK": "344", "HM": "334",
+	"HN": "340", "HR": "191", "HT": "332", "HU": "348", "ID": "360", "IE": "372", "IL": "376", "IM": "833",
+	"IN": "356", "IO": "086", "IQ": "368", "IR": "364", "IS": "352", "IT": "380", "JE": "832", "JM": "388",
+	"JO": "400", "JP": "392", "KE": "404", "KG": "417", "KH": "116", "KI": "296", "KM": "174", "KN": "659",
+	"KP": "408", "KR": "410", "KW": "414", "KY": "136", "KZ": "398", "LA": "418", "LB": "422", "LC": "662",
+	"LI": "438", "LK": "144", "LR": "430", "LS": "426", "LT": "440", "LU": "442", "LV": "428", "LY": "434",
+	"MA": "504", "MC": "492", "MD": "498", "ME": "499", "MF": "663", "MG": "450", "MH": "584", "MK": "807",
+	"ML": "466", "MM": "104", "MN": "496", "MO": "446", "MP": "580", "MQ": "474", "MR": "478", "MS": "500",
+	"MT": "470", "MU": "480", "MV": "462", "MW": "454", "MX": "484", "MY": "458", "MZ": "508", "NA": "516",
+	"NC": "540", "NE": "562", "NF": "574", "NG": "566", "NI": "558", "NL": "528", "NO": "578", "NP": "524",
+	"NR": "520", "NU": "570", "NZ": "554", "OM": "512", "PA": "591", "PE": "604", "PF": "258", "PG": "598",
+	"PH": "608", "PK": "586", "PL": "616", "PM": "666", "PN": "612", "PR": "630", "PS": "275", "PT": "620",
+	"PW": "585", "PY": "600", "QA": "634", "RE": "638", "RO": "642", "RS": "688", "RU": "643", "RW": "646",
+	"SA": "682", "SB": "090", "SC": "690", "SD": "729", "SE": "752", "SG": "702", "SH": "654", "SI": "705",
+	"SJ": "744", "SK": "703", "SL": "694", "SM": "674", "SN": "686", "SO": "706", "SR": "740", "SS": "728",
+	"ST": "678", "SV": "222", "SX": "534", "SY": "760", "SZ": "748", "TC": "796", "TD": "148", "TF": "260",
+	"TG": "768", "TH": "764", "TJ": "762", "TK": "772", "TL": "626", "TM": "795", "TN": "788", "TO": "776",
+	"TR": "792", "TT": "780", "TV": "798", "TW": "158", "TZ": "834", "UA": "804", "UG": "800", "UM": "581",
+	"US": "840", "UY": "858", "UZ": "860", "VA": "336", "VC": "670", "VE": "862", "VG": "092", "VI": "850",
+	"VN": "704", "VU": "548", "WF": "876", "WS": "882", "YE": "887", "YT": "175", "ZA": "710", "ZM": "894",
+	"ZW": "716",
+}
+
+// AccountAge is the time the customer has had an account with the merchant.
+type AccountAge string
+
+const (
+	AccountAgeGuest          = AccountAge("01")
+	AccountAgeThisPayment    = AccountAge("02")
+	AccountAgeLess30Days     = AccountAge("03")
+	AccountAgeFrom30To60Days = AccountAge("04")
+	AccountAgeMore60Days     = AccountAge("05")
+)
+
+// Cardholder data sent to the issuer during the EMV3DS authentication. The more data is sent the more likely it
+// is that the issuer authenticates the payment without a challenge. All fields are optional.
+type Cardholder struct {
+	// Email of the customer.
+	Email string
+
+	// Name of the cardholder. It should have between 2 and 45 characters.
+	Name string
+
+	// Phones of the customer.
+	HomePhone   *Phone
+	MobilePhone *Phone
+	WorkPhone   *Phone
+
+	// Billing address of the customer.
+	BillingAddress *Address
+
+	// Shipping address of the order. Leave empty if the order does not have one.
+	ShippingAddress *Address
+
+	// Account of the customer in the merchant.
+	Account *CardholderAccount
+}
+
+// Phone of the customer.
+type Phone struct {
+	// Country calling code without the plus sign, e.g. "34".
+	CountryCode string
+
+	// Subscriber number with only digits.
+	Subscriber string
+}
+
+// Address of the customer.
+type Address struct {
+	Line1    string
+	Line2    string
+	Line3    string
+	City     string
+	PostCode string
+
+	// ISO 3166-2 subdivision code of the state without the country prefix, e.g. "M" for Madrid.
+	State string
+
+	// ISO 3166-1 country code, both alpha-2 (e.g. "ES") and numeric (e.g. "724") codes are accepted.
+	Country string
+}
+
+// CardholderAccount is the account of the customer in the merchant.
+type CardholderAccount struct {
+	// Identifier of the account in the merchant.
+	ID string
+
+	// Time the customer has had the account.
+	Age AccountAge
+
+	// Date when the account was created.
+	Created time.Time
+
+	// Date when the account was last changed.
+	Changed time.Time
+
+	// Date when the password of the account was last changed.
+	PasswordChanged time.Time
+
+	// Number of purchases of the account in the last six months.
+	PurchasesLast6Months int
+}
+
+type phoneRequest struct {
+	CC         string `json:"cc"`
+	Subscriber string `json:"subscriber"`
+}
+
+type accountInfoRequest struct {
+	AgeInd            AccountAge `json:"chAccAgeInd,omitempty"`
+	Date              string     `json:"chAccDate,omitempty"`
+	Change            string     `json:"chAccChange,omitempty"`
+	PwChange          string     `json:"chAccPwChange,omitempty"`
+	NbPurchaseAccount string     `json:"nbPurchaseAccount,omitempty"`
+}
+
+type cardholderRequest struct {
+	Email            string              `json:"email,omitempty"`
+	CardholderName   string              `json:"cardholderName,omitempty"`
+	HomePhone        *phoneRequest       `json:"homePhone,omitempty"`
+	MobilePhone      *phoneRequest       `json:"mobilePhone,omitempty"`
+	WorkPhone        *phoneRequest       `json:"workPhone,omitempty"`
+	BillAddrLine1    string              `json:"billAddrLine1,omitempty"`
+	BillAddrLine2    string              `json:"billAddrLine2,omitempty"`
+	BillAddrLine3    string              `json:"billAddrLine3,omitempty"`
+	BillAddrCity     string              `json:"billAddrCity,omitempty"`
+	BillAddrPostCode string              `json:"billAddrPostCode,omitempty"`
+	BillAddrState    string              `json:"billAddrState,omitempty"`
+	BillAddrCountry  string              `json:"billAddrCountry,omitempty"`
+	ShipAddrLine1    string              `json:"shipAddrLine1,omitempty"`
+	ShipAddrLine2    string              `json:"shipAddrLine2,omitempty"`
+	ShipAddrLine3    string              `json:"shipAddrLine3,omitempty"`
+	ShipAddrCity     string              `json:"shipAddrCity,omitempty"`
+	ShipAddrPostCode string              `json:"shipAddrPostCode,omitempty"`
+	ShipAddrState    string              `json:"shipAddrState,omitempty"`
+	ShipAddrCountry  string              `json:"shipAddrCountry,omitempty"`
+	AddrMatch        string              `json:"addrMatch,omitempty"`
+	AcctID           string              `json:"acctID,omitempty"`
+	AcctInfo         *accountInfoRequest `json:"acctInfo,omitempty"`
+}
+
+var (
+	reEmail           = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	rePhoneCC         = regexp.MustCompile(`^[0-9]{1,3}$`)
+	rePhoneSubscriber = regexp.MustCompile(`^[0-9]{1,15}$`)
+)
+
+func (cardholder *Cardholder) request() (*cardholderRequest, error) {
+	if cardholder == nil {
+		return nil, nil
+	}
+
+	req := new(cardholderRequest)
+	if cardholder.Email != "" {
+		if len(cardholder.Email) > 254 || !reEmail.MatchString(cardholder.Email) {
+			return nil, fmt.Errorf("invalid cardholder email %q", cardholder.Email)
+		}
+		req.Email = cardholder.Email
+	}
+	if cardholder.Name != "" {
+		if n := utf8.RuneCountInString(cardholder.Name); n < 2 || n > 45 {
+			return nil, fmt.Errorf("invalid cardholder name %q: it should have between 2 and 45 characters", cardholder.Name)
+		}
+		req.CardholderName = cardholder.Name
+	}
+
+	var err error
+	if req.HomePhone, err = cardholder.HomePhone.request("home"); err != nil {
+		return nil, err
+	}
+	if req.MobilePhone, err = cardholder.MobilePhone.request("mobile"); err != nil {
+		return nil, err
+	}
+	if req.WorkPhone, err = cardholder.WorkPhone.request("work"); err != nil {
+		return nil, err
+	}
+
+	if addr := cardholder.BillingAddress; addr != nil {
+		country, err := addr.validate("billing")
+		if err != nil {
+			return nil, err
+		}
+		req.BillAddrLine1 = addr.Line1
+		req.BillAddrLine2 = addr.Line2
+		req.BillAddrLine3 = addr.Line3
+		req.BillAddrCity = addr.City
+		req.BillAddrPostCode = addr.PostCode
+		req.BillAddrState = addr.State
+		req.BillAddrCountry = country
+	}
+	if addr := cardholder.ShippingAddress; addr != nil {
+		country, err := addr.validate("shipping")
+		if err != nil {
+			return nil, err
+		}
+		req.ShipAddrLine1 = addr.Line1
+		req.ShipAddrLine2 = addr.Line2
+		req.ShipAddrLine3 = addr.Line3
+		req.ShipAddrCity = addr.City
+		req.ShipAddrPostCode = addr.PostCode
+		req.ShipAddrState = addr.State
+		req.ShipAddrCountry = country
+	}
+	if cardholder.BillingAddress != nil && cardholder.ShippingAddress != nil {
+		req.AddrMatch = "N"
+		if *cardholder.BillingAddress == *cardholder.ShippingAddress {
+			req.AddrMatch = "Y"
+		}
+	}
+
+	if account := cardholder.Account; account != nil {
+		if utf8.RuneCountInString(account.ID) > 64 {
+			return nil, fmt.Errorf("invalid cardholder account ID %q: it should have 64 characters or less", account.ID)
+		}
+		switch account.Age {
+		case "", AccountAgeGuest, AccountAgeThisPayment, AccountAgeLess30Days, AccountAgeFrom30To60Days, AccountAgeMore60Days:
+		default:
+			return nil, fmt.Errorf("unknown cardholder account age %q", account.Age)
+		}
+		if account.PurchasesLast6Months < 0 || account.PurchasesLast6Months > 9999 {
+			return nil, fmt.Errorf("invalid cardholder account purchases %d", account.PurchasesLast6Months)
+		}
+		req.AcctID = account.ID
+		req.AcctInfo = &accountInfoRequest{
+			AgeInd:   account.Age,
+			Date:     formatAccountDate(account.Created),
+			Change:   formatAccountDate(account.Changed),
+			PwChange: formatAccountDate(account.PasswordChanged),
+		}
+		if account.PurchasesLast6Months > 0 {
+			req.AcctInfo.NbPurchaseAccount = fmt.Sprintf("%d", account.PurchasesLast6Months)
+		}
+	}
+
+	return req, nil
+}
+
+func (phone *Phone) request(kind string) (*phoneRequest, error) {
+	if phone == nil {
+		return nil, nil
+	}
+	if !rePhoneCC.MatchString(phone.CountryCode) {
+		return nil, fmt.Errorf("invalid cardholder %s phone country code %q", kind, phone.CountryCode)
+	}
+	if !rePhoneSubscriber.MatchString(phone.Subscriber) {
+		return nil, fmt.Errorf("invalid cardholder %s phone number %q", kind, phone.Subscriber)
+	}
+	return &phoneRequest{CC: phone.CountryCode, Subscriber: phone.Subscriber}, nil
+}
+
+// validate checks the lengths of the address and returns the numeric country code.
+func (addr *Address) validate(kind string) (string, error) {
+	fields := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"line 1", addr.Line1, 50},
+		{"line 2", addr.Line2, 50},
+		{"line 3", addr.Line3, 50},
+		{"city", addr.City, 50},
+		{"post code", addr.PostCode, 16},
+		{"state", addr.State, 3},
+	}
+	for _, field := range fields {
+		if utf8.RuneCountInString(field.value) > field.max {
+			return "", fmt.Errorf("invalid cardholder %s address %s %q: it should have %d characters or less", kind, field.name, field.value, field.max)
+		}
+	}
+	if addr.Country == "" {
+		return "", nil
+	}
+	country, ok := countryCode(addr.Country)
+	if !ok {
+		return "", fmt.Errorf("unknown cardholder %s address country %q", kind, addr.Country)
+	}
+	return country, nil
+}
+
+func countryCode(country string) (string, bool) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if numeric, ok := countries[country]; ok {
+		return numeric, true
+	}
+	for _, numeric := range countries {
+		if numeric == country {
+			return numeric, true
+		}
+	}
+	return "", false
+}
+
+func formatAccountDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("20060102")
+}
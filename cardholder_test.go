@@ -0,0 +1,150 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignCardholder(t *testing.T) {
+	merchant := Merchant{
+		Secret: "sq7HjrUOBfKmC576ILgskD5srU870gJ7",
+	}
+	address := &Address{
+		Line1:    "Calle Mayor 1",
+		City:     "Madrid",
+		PostCode: "28013",
+		State:    "M",
+		Country:  "ES",
+	}
+	session := Session{
+		Order: "00011234abcd",
+		Cardholder: &Cardholder{
+			Email:           "john@example.com",
+			Name:            "John Doe",
+			MobilePhone:     &Phone{CountryCode: "34", Subscriber: "600000000"},
+			BillingAddress:  address,
+			ShippingAddress: address,
+			Account: &CardholderAccount{
+				ID:      "user-1234",
+				Age:     AccountAgeMore60Days,
+				Created: time.Date(2020, time.May, 4, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	signed, err := Sign(context.Background(), merchant, session)
+	require.NoError(t, err)
+
+	decoded, err := base64.StdEncoding.DecodeString(signed.Params)
+	require.NoError(t, err)
+	params := map[string]interface{}{}
+	err = json.Unmarshal(decoded, &params)
+	require.NoError(t, err)
+
+	require.Equal(t, params["Ds_Merchant_EMV3DS"], map[string]interface{}{
+		"email":            "john@example.com",
+		"cardholderName":   "John Doe",
+		"mobilePhone":      map[string]interface{}{"cc": "34", "subscriber": "600000000"},
+		"billAddrLine1":    "Calle Mayor 1",
+		"billAddrCity":     "Madrid",
+		"billAddrPostCode": "28013",
+		"billAddrState":    "M",
+		"billAddrCountry":  "724",
+		"shipAddrLine1":    "Calle Mayor 1",
+		"shipAddrCity":     "Madrid",
+		"shipAddrPostCode": "28013",
+		"shipAddrState":    "M",
+		"shipAddrCountry":  "724",
+		"addrMatch":        "Y",
+		"acctID":           "user-1234",
+		"acctInfo": map[string]interface{}{
+			"chAccAgeInd": "05",
+			"chAccDate":   "20200504",
+		},
+	})
+}
+
+func TestSignWithoutCardholder(t *testing.T) {
+	merchant := Merchant{
+		Secret: "sq7HjrUOBfKmC576ILgskD5srU870gJ7",
+	}
+	session := Session{
+		Order: "00011234abcd",
+	}
+	signed, err := Sign(context.Background(), merchant, session)
+	require.NoError(t, err)
+
+	decoded, err := base64.StdEncoding.DecodeString(signed.Params)
+	require.NoError(t, err)
+	params := map[string]interface{}{}
+	err = json.Unmarshal(decoded, &params)
+	require.NoError(t, err)
+
+	require.NotContains(t, params, "Ds_Merchant_EMV3DS")
+}
+
+func TestCardholderValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		cardholder *Cardholder
+		err        string
+	}{
+		{
+			name:       "email",
+			cardholder: &Cardholder{Email: "john.example.com"},
+			err:        `invalid cardholder email "john.example.com"`,
+		},
+		{
+			name:       "name",
+			cardholder: &Cardholder{Name: "J"},
+			err:        `invalid cardholder name "J": it should have between 2 and 45 characters`,
+		},
+		{
+			name:       "phone country code",
+			cardholder: &Cardholder{HomePhone: &Phone{CountryCode: "+34", Subscriber: "910000000"}},
+			err:        `invalid cardholder home phone country code "+34"`,
+		},
+		{
+			name:       "phone subscriber",
+			cardholder: &Cardholder{WorkPhone: &Phone{CountryCode: "34", Subscriber: "910 00 00 00"}},
+			err:        `invalid cardholder work phone number "910 00 00 00"`,
+		},
+		{
+			name:       "address line",
+			cardholder: &Cardholder{BillingAddress: &Address{Line1: "123456789012345678901234567890123456789012345678901"}},
+			err:        `invalid cardholder billing address line 1 "123456789012345678901234567890123456789012345678901": it should have 50 characters or less`,
+		},
+		{
+			name:       "address country",
+			cardholder: &Cardholder{ShippingAddress: &Address{Country: "XX"}},
+			err:        `unknown cardholder shipping address country "XX"`,
+		},
+		{
+			name:       "account age",
+			cardholder: &Cardholder{Account: &CardholderAccount{Age: "06"}},
+			err:        `unknown cardholder account age "06"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.cardholder.request()
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestCardholderNumericCountry(t *testing.T) {
+	req, err := (&Cardholder{
+		BillingAddress:  &Address{Country: "826"},
+		ShippingAddress: &Address{Country: "us"},
+	}).request()
+	require.NoError(t, err)
+
+	require.Equal(t, req.BillAddrCountry, "826")
+	require.Equal(t, req.ShipAddrCountry, "840")
+	require.Equal(t, req.AddrMatch, "N")
+}
@@ -0,0 +1,212 @@
+package redsys
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Classifier decides the status of the operations from the response code of the bank.
+type Classifier interface {
+	// Classify returns the status and the detailed outcome of the parameters of an operation.
+	Classify(params Params) (status, outcome Status)
+}
+
+// Response codes classified as StatusCancelled by DefaultClassifier.
+var legacyCancelledCodes = []int64{
+	101,  // Tarjeta caducada, no reintentar la operación.
+	102,  // Tarjeta inválida, no reintentar la operación.
+	104,  // Operación no permitida para esa tarjeta, consulte con la entidad emisora de la misma.
+	118,  // Tarjeta no registrada, no reintentar la operación.
+	129,  // Código de seguridad (CVV2/CVC2) incorrecto.
+	172,  // Denegada, no repetir.
+	180,  // Tarjeta ajena al servicio.
+	190,  // Denegación del emisor sin especificar motivo.
+	184,  // Error en la autenticación del titular.
+	191,  // Fecha de caducidad errónea.
+	9080, // Error genérico. Consulte con Soporte.
+	9142, // Tiempo excecido para el pago.
+	9221, // El CVV2 es obligatorio.
+	9593, // Error en la operacion de autenticacion EMV3DS,el transStatus de la consulta final de la operación no está definido.
+	9599, // Error en la operacion de autenticacion EMV3DS.
+	9602, // Error en el proceso de autenticación 3DSecure v2 – Respuesta Areq U.
+	9673, // Operación cancelada. El usuario no desea seguir.
+	9754, // La tarjeta no permite autenticación en versión 2.
+	9915, // A petición del usuario se ha cancelado el pago.
+	9589, // Operacion de autenticacion EMV3DS rechazada, respuesta sin CRes.
+	9590, // Operacion de autenticacion EMV3DS rechazada, error al desmontar la respuesta CRes.
+	9600, // El banco emisor indica que no es posible autenticar la tarjeta – Respuesta Areq N.
+	9601, // El banco emisor indica que no es posible autenticar la tarjeta – Respuesta Areq R.
+}
+
+// DefaultClassifier is the classification used by Confirm. The outcome comes from the category of the response in
+// the catalog.
+type DefaultClassifier struct{}
+
+// Classify implements Classifier.
+func (DefaultClassifier) Classify(params Params) (Status, Status) {
+	var info ResponseInfo
+	if params.RawResponse != "" {
+		info, _ = lookupResponse(params.Response, params.IsBizum())
+	}
+	outcome := info.Category.Status()
+
+	switch {
+	case params.Response == 913 || params.Response == 9051:
+		return StatusRepeated, outcome
+
+	case slices.Contains(legacyCancelledCodes, params.Response):
+		return StatusCancelled, outcome
+
+	case params.Response >= 0 && params.Response <= 99:
+		return StatusApproved, outcome
+
+	case params.Response == 400 || params.Response == 900:
+		// Refunds, confirmations and cancellations are approved with their own codes.
+		return StatusApproved, outcome
+	}
+	return StatusUnknown, outcome
+}
+
+// Rule of a RulesClassifier.
+type Rule struct {
+	// Response codes of the rule.
+	Codes []int64 `json:"codes"`
+
+	// Status of the operations with any of the codes: StatusUnknown, StatusApproved, StatusCancelled or
+	// StatusRepeated. StatusUnknown can also be written as "unknown" because it is empty.
+	Status Status `json:"status"`
+
+	// Detailed outcome of the operations with any of the codes. Besides the statuses it can also be "denied",
+	// "fraud", "error" or "pending". By default it will be the same as Status if empty.
+	Outcome Status `json:"outcome"`
+}
+
+// RulesClassifier overrides the classification of some response codes with a table of rules.
+type RulesClassifier struct {
+	// Rules to apply. The first rule that contains the code wins. Invalid rules are ignored, build the classifier
+	// with NewRulesClassifier to report them.
+	Rules []Rule
+
+	// Classifier of the codes without rules. By default it will be DefaultClassifier if empty.
+	Fallback Classifier
+}
+
+// statusUnknownRule is the name of StatusUnknown in the rules, because the status itself is empty.
+const statusUnknownRule = Status("unknown")
+
+var ruleStatuses = []Status{
+	StatusUnknown,
+	statusUnknownRule,
+	StatusApproved,
+	StatusCancelled,
+	StatusRepeated,
+}
+
+// The detailed statuses are only reported in Operation.Outcome.
+var ruleOutcomes = []Status{
+	statusUnknownRule,
+	StatusApproved,
+	StatusCancelled,
+	StatusRepeated,
+	StatusDenied,
+	StatusFraud,
+	StatusError,
+	StatusPending,
+}
+
+// LoadRules reads a JSON table of rules like:
+//
+//	[
+//	  {"codes": [9080], "status": "cancelled", "outcome": "error"},
+//	  {"codes": [116, 190], "status": "cancelled", "outcome": "denied"}
+//	]
+func LoadRules(r io.Reader) (*RulesClassifier, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var rules []Rule
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("cannot decode rules: %v", err)
+	}
+	return NewRulesClassifier(rules)
+}
+
+// NewRulesClassifier checks the rules and returns a classifier that applies them. The detailed statuses like
+// StatusDenied are only accepted in the outcome of the rules.
+func NewRulesClassifier(rules []Rule) (*RulesClassifier, error) {
+	for i, rule := range rules {
+		if err := rule.check(); err != nil {
+			return nil, fmt.Errorf("rule %d %v", i, err)
+		}
+	}
+	return &RulesClassifier{Rules: rules}, nil
+}
+
+func (rule Rule) check() error {
+	if len(rule.Codes) == 0 {
+		return fmt.Errorf("without codes")
+	}
+	if !slices.Contains(ruleStatuses, rule.Status) {
+		return fmt.Errorf("with invalid status %q", rule.Status)
+	}
+	if rule.Outcome != "" && !slices.Contains(ruleOutcomes, rule.Outcome) {
+		return fmt.Errorf("with invalid outcome %q", rule.Outcome)
+	}
+	return nil
+}
+
+// Classify implements Classifier.
+func (classifier *RulesClassifier) Classify(params Params) (Status, Status) {
+	if params.RawResponse != "" {
+		for _, rule := range classifier.Rules {
+			if !slices.Contains(rule.Codes, params.Response) || rule.check() != nil {
+				continue
+			}
+			outcome := rule.Outcome
+			if outcome == "" {
+				outcome = rule.Status
+			}
+			return ruleStatus(rule.Status), ruleStatus(outcome)
+		}
+	}
+	return classifierOrDefault(classifier.Fallback).Classify(params)
+}
+
+func ruleStatus(status Status) Status {
+	if status == statusUnknownRule {
+		return StatusUnknown
+	}
+	return status
+}
+
+// MerchantClassifier uses a different classification for each merchant.
+type MerchantClassifier struct {
+	// Classifiers by merchant code.
+	Merchants map[string]Classifier
+
+	// Classifier of the rest of merchants. By default it will be DefaultClassifier if empty.
+	Default Classifier
+}
+
+// Classify implements Classifier.
+func (classifier *MerchantClassifier) Classify(params Params) (Status, Status) {
+	if merchant, ok := classifier.Merchants[params.MerchantCode]; ok {
+		return merchant.Classify(params)
+	}
+	return classifierOrDefault(classifier.Default).Classify(params)
+}
+
+func classifierOrDefault(classifier Classifier) Classifier {
+	if classifier == nil {
+		return DefaultClassifier{}
+	}
+	return classifier
+}
+
+// Reclassify returns a copy of the operation with the status and outcome decided by the classifier.
+func (operation Operation) Reclassify(classifier Classifier) Operation {
+	operation.Status, operation.Outcome = classifier.Classify(operation.Params)
+	operation.IsCreditCard = operation.Status == StatusApproved && operation.Params.Response >= 0 && operation.Params.Response <= 99 && operation.Params.CardType == "C"
+	return operation
+}
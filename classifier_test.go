@@ -0,0 +1,161 @@
+package redsys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultClassifier(t *testing.T) {
+	tests := []struct {
+		response string
+		status   Status
+		outcome  Status
+	}{
+		{"0000", StatusApproved, StatusApproved},
+		{"0190", StatusCancelled, StatusDenied},
+		{"9080", StatusCancelled, StatusError},
+		{"9051", StatusRepeated, StatusRepeated},
+		{"0116", StatusUnknown, StatusDenied},
+		{"", StatusApproved, StatusUnknown},
+	}
+	for _, test := range tests {
+		params := Params{RawResponse: test.response}
+		require.NoError(t, params.parseRaw())
+		status, outcome := DefaultClassifier{}.Classify(params)
+		require.Equal(t, status, test.status, test.response)
+		require.Equal(t, outcome, test.outcome, test.response)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	classifier, err := LoadRules(strings.NewReader(`[
+		{"codes": [9080], "status": "unknown", "outcome": "error"},
+		{"codes": [116, 190], "status": "cancelled", "outcome": "denied"}
+	]`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		response string
+		status   Status
+		outcome  Status
+	}{
+		{"9080", StatusUnknown, StatusError},
+		{"0116", StatusCancelled, StatusDenied},
+		{"0190", StatusCancelled, StatusDenied},
+		{"9915", StatusCancelled, StatusCancelled},
+	}
+	for _, test := range tests {
+		params := Params{RawResponse: test.response}
+		require.NoError(t, params.parseRaw())
+		status, outcome := classifier.Classify(params)
+		require.Equal(t, status, test.status, test.response)
+		require.Equal(t, outcome, test.outcome, test.response)
+	}
+}
+
+func TestLoadRulesEmptyStatus(t *testing.T) {
+	classifier, err := LoadRules(strings.NewReader(`[{"codes": [190], "status": ""}]`))
+	require.NoError(t, err)
+
+	params := Params{RawResponse: "0190"}
+	require.NoError(t, params.parseRaw())
+	status, outcome := classifier.Classify(params)
+	require.Equal(t, status, StatusUnknown)
+	require.Equal(t, outcome, StatusUnknown)
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	_, err := LoadRules(strings.NewReader(`[{"codes": [9080], "status": "rejected"}]`))
+	require.EqualError(t, err, `rule 0 with invalid status "rejected"`)
+
+	_, err = LoadRules(strings.NewReader(`[{"codes": [9080], "status": "fraud"}]`))
+	require.EqualError(t, err, `rule 0 with invalid status "fraud"`)
+
+	_, err = LoadRules(strings.NewReader(`[{"codes": [9080], "status": "cancelled", "outcome": "bad"}]`))
+	require.EqualError(t, err, `rule 0 with invalid outcome "bad"`)
+
+	_, err = LoadRules(strings.NewReader(`[{"status": "cancelled"}]`))
+	require.EqualError(t, err, `rule 0 without codes`)
+
+	_, err = LoadRules(strings.NewReader(`[{"code": [9080], "status": "cancelled"}]`))
+	require.ErrorContains(t, err, "cannot decode rules")
+}
+
+func TestMerchantClassifier(t *testing.T) {
+	classifier := &MerchantClassifier{
+		Merchants: map[string]Classifier{
+			"123456789": &RulesClassifier{
+				Rules: []Rule{{Codes: []int64{190}, Status: StatusUnknown, Outcome: StatusError}},
+			},
+		},
+	}
+
+	params := Params{RawResponse: "0190", MerchantCode: "123456789"}
+	require.NoError(t, params.parseRaw())
+	status, outcome := classifier.Classify(params)
+	require.Equal(t, status, StatusUnknown)
+	require.Equal(t, outcome, StatusError)
+
+	params.MerchantCode = "987654321"
+	status, outcome = classifier.Classify(params)
+	require.Equal(t, status, StatusCancelled)
+	require.Equal(t, outcome, StatusDenied)
+}
+
+func TestOperationReclassify(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "9080"})
+	operation, err := Confirm(context.Background(), testSecret, signed)
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusCancelled)
+
+	classifier := &RulesClassifier{
+		Rules: []Rule{{Codes: []int64{9080}, Status: StatusUnknown, Outcome: StatusError}},
+	}
+	reclassified := operation.Reclassify(classifier)
+	require.Equal(t, reclassified.Status, StatusUnknown)
+	require.Equal(t, reclassified.Outcome, StatusError)
+	require.Equal(t, operation.Status, StatusCancelled)
+}
+
+func TestRulesClassifierInvalidRules(t *testing.T) {
+	rules := []Rule{{Codes: []int64{9080}, Status: StatusError}}
+	_, err := NewRulesClassifier(rules)
+	require.EqualError(t, err, `rule 0 with invalid status "error"`)
+
+	params := Params{RawResponse: "9080"}
+	require.NoError(t, params.parseRaw())
+	status, outcome := (&RulesClassifier{Rules: rules}).Classify(params)
+	require.Equal(t, status, StatusCancelled)
+	require.Equal(t, outcome, StatusError)
+}
+
+func TestHandlerClassifier(t *testing.T) {
+	var called []Status
+	handler := &Handler{
+		Secret: testSecret,
+		Classifier: &RulesClassifier{
+			Rules: []Rule{{Codes: []int64{9080}, Status: statusUnknownRule}},
+		},
+		OnUnknown: func(ctx context.Context, operation Operation) error {
+			called = append(called, operation.Status)
+			return nil
+		},
+		OnCancelled: func(ctx context.Context, operation Operation) error {
+			t.Fatal("should not be called")
+			return nil
+		},
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "9080"})
+
+	r := httptest.NewRequest(http.MethodGet, "/notification?"+notificationForm(signed).Encode(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, w.Code, http.StatusOK)
+	require.Equal(t, called, []Status{StatusUnknown})
+}
@@ -0,0 +1,217 @@
+package redsys
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	BaseURLProduction = "https://sis.redsys.es"
+	BaseURLDebug      = "https://sis-t.redsys.es:25443"
+)
+
+const pathREST = "/sis/rest/trataPeticionREST"
+
+// Client sends server-to-server operations to the REST endpoint of the bank.
+type Client struct {
+	// HTTP client to use in the requests. By default it will be http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Base URL of the bank. By default it will be the production or debug server depending on the merchant.
+	BaseURL string
+
+	now func() time.Time
+}
+
+func (client *Client) currentTime() time.Time {
+	if client.now != nil {
+		return client.now()
+	}
+	return time.Now()
+}
+
+// Request of a server-to-server operation.
+type Request struct {
+	// Transaction type of the operation.
+	TransactionType TransactionType `json:"Ds_Merchant_TransactionType"`
+
+	// Code of the order. It should have the same format as the Session order.
+	Order string `json:"Ds_Merchant_Order"`
+
+	// Amount in the minor unit of the merchant currency, e.g. cents for euros.
+	Amount int32 `json:"Ds_Merchant_Amount"`
+
+	// Raw custom data that will be sent back in the response.
+	Data string `json:"Ds_Merchant_MerchantData,omitempty"`
+
+	// Payment method of the operation. By default it will be credit card if empty.
+	PaymentMethod PaymentMethod `json:"Ds_Merchant_PayMethods,omitempty"`
+
+	// Card token previously returned by the bank to charge the card without the customer.
+	Identifier string `json:"Ds_Merchant_Identifier,omitempty"`
+
+	// Charge the token directly without authenticating the customer again.
+	DirectPayment bool `json:"Ds_Merchant_DirectPayment,string,omitempty"`
+
+	// Card on file configuration for payments with stored credentials.
+	CardOnFile *CardOnFile `json:"-"`
+
+	// Card number for direct integrations. It requires PCI DSS compliance from the merchant.
+	Pan string `json:"Ds_Merchant_Pan,omitempty"`
+
+	// Expiry date of the card in the format "YYMM" for direct integrations.
+	ExpiryDate string `json:"Ds_Merchant_ExpiryDate,omitempty"`
+
+	// Security code of the card for direct integrations.
+	CVV2 string `json:"Ds_Merchant_Cvv2,omitempty"`
+
+	// Cardholder data to reduce the number of challenges of the EMV3DS authentication.
+	Cardholder *Cardholder `json:"-"`
+
+	// Operation ID returned by the InSite form in the browser instead of the card data.
+	IDOper string `json:"Ds_Merchant_IdOper,omitempty"`
+}
+
+type restRequest struct {
+	MerchantCode string   `json:"Ds_Merchant_MerchantCode"`
+	Terminal     int64    `json:"Ds_Merchant_Terminal"`
+	Currency     Currency `json:"Ds_Merchant_Currency"`
+	Request
+	*cofRequest
+	EMV3DS *emv3dsRequest `json:"Ds_Merchant_EMV3DS,omitempty"`
+}
+
+type restMessage struct {
+	SignatureVersion string `json:"Ds_SignatureVersion,omitempty"`
+	Params           string `json:"Ds_MerchantParameters,omitempty"`
+	Signature        string `json:"Ds_Signature,omitempty"`
+	ErrorCode        string `json:"errorCode,omitempty"`
+}
+
+// SISError is an error returned by the bank when the request cannot be processed at all.
+type SISError struct {
+	// Code of the error, e.g. "SIS0051".
+	Code string
+}
+
+func (err *SISError) Error() string {
+	return fmt.Sprintf("sis error: %s", err.Code)
+}
+
+// Send signs the request with the merchant data, sends it to the bank and verifies the signed response. If the
+// bank rejects the request itself a *SISError will be returned.
+func (client *Client) Send(ctx context.Context, merchant Merchant, req Request) (Operation, error) {
+	params, err := client.do(ctx, merchant, pathREST, req, nil)
+	if err != nil {
+		return Operation{}, err
+	}
+	return newServerOperation(params)
+}
+
+func (client *Client) do(ctx context.Context, merchant Merchant, path string, req Request, emv3ds *emv3dsRequest) (Params, error) {
+	if !reOrder.MatchString(req.Order) {
+		return Params{}, fmt.Errorf("invalid order format %q", req.Order)
+	}
+	currency, err := merchant.currency()
+	if err != nil {
+		return Params{}, err
+	}
+
+	cof, err := req.CardOnFile.request()
+	if err != nil {
+		return Params{}, err
+	}
+	cardholder, err := req.Cardholder.request()
+	if err != nil {
+		return Params{}, err
+	}
+	if cardholder != nil {
+		if emv3ds == nil {
+			emv3ds = new(emv3dsRequest)
+		}
+		emv3ds.cardholderRequest = cardholder
+	}
+
+	paramsJSON, err := json.Marshal(restRequest{
+		MerchantCode: merchant.Code,
+		Terminal:     merchant.Terminal,
+		Currency:     currency,
+		Request:      req,
+		cofRequest:   cof,
+		EMV3DS:       emv3ds,
+	})
+	if err != nil {
+		return Params{}, fmt.Errorf("cannot marshal params: %v", err)
+	}
+	paramsStr := base64.URLEncoding.EncodeToString(paramsJSON)
+	signature, err := merchant.signer().Sign(ctx, req.Order, paramsStr)
+	if err != nil {
+		return Params{}, fmt.Errorf("%v", err)
+	}
+	body, err := json.Marshal(restMessage{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           paramsStr,
+		Signature:        base64.URLEncoding.EncodeToString(signature),
+	})
+	if err != nil {
+		return Params{}, fmt.Errorf("cannot marshal request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURLOrDefault(client.BaseURL, merchant)+path, bytes.NewReader(body))
+	if err != nil {
+		return Params{}, fmt.Errorf("cannot prepare request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := httpClientOrDefault(client.HTTPClient).Do(httpReq)
+	if err != nil {
+		return Params{}, fmt.Errorf("cannot send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Params{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var reply restMessage
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return Params{}, fmt.Errorf("cannot decode response: %v", err)
+	}
+	if reply.ErrorCode != "" {
+		return Params{}, &SISError{Code: reply.ErrorCode}
+	}
+
+	params, _, err := verify(ctx, merchant.keys(), Signed{
+		SignatureVersion: reply.SignatureVersion,
+		Params:           reply.Params,
+		Signature:        reply.Signature,
+	})
+	if err != nil {
+		return Params{}, err
+	}
+	if params.Order != req.Order {
+		return Params{}, fmt.Errorf("unexpected order %q in the response of order %q", params.Order, req.Order)
+	}
+	return params, nil
+}
+
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
+
+func baseURLOrDefault(baseURL string, merchant Merchant) string {
+	switch {
+	case baseURL != "":
+		return baseURL
+	case merchant.Debug:
+		return BaseURLDebug
+	default:
+		return BaseURLProduction
+	}
+}
@@ -0,0 +1,167 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "sq7HjrUOBfKmC576ILgskD5srU870gJ7"
+
+func testMerchant() Merchant {
+	return Merchant{
+		Code:     "123456789",
+		Terminal: 1,
+		Secret:   testSecret,
+	}
+}
+
+// newRESTServer emulates the REST endpoint of the bank. It verifies the signature of the request and signs the
+// response returned by the reply function. A response with the errorCode key is sent without signature.
+func newRESTServer(t *testing.T, reply func(path string, params map[string]interface{}) map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg restMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		require.Equal(t, msg.SignatureVersion, "HMAC_SHA256_V1")
+
+		decoded, err := base64.URLEncoding.DecodeString(msg.Params)
+		require.NoError(t, err)
+		params := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(decoded, &params))
+
+		signature, err := sign(testSecret, params["Ds_Merchant_Order"].(string), msg.Params)
+		require.NoError(t, err)
+		require.Equal(t, msg.Signature, base64.URLEncoding.EncodeToString(signature))
+
+		response := reply(r.URL.Path, params)
+		if code, ok := response["errorCode"]; ok {
+			require.NoError(t, json.NewEncoder(w).Encode(restMessage{ErrorCode: code.(string)}))
+			return
+		}
+		responseJSON, err := json.Marshal(response)
+		require.NoError(t, err)
+		responseStr := base64.StdEncoding.EncodeToString(responseJSON)
+		signature, err = sign(testSecret, response["Ds_Order"].(string), responseStr)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(w).Encode(restMessage{
+			SignatureVersion: "HMAC_SHA256_V1",
+			Params:           responseStr,
+			Signature:        base64.StdEncoding.EncodeToString(signature),
+		}))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientSend(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		require.Equal(t, path, "/sis/rest/trataPeticionREST")
+		require.Equal(t, params, map[string]interface{}{
+			"Ds_Merchant_MerchantCode":    "123456789",
+			"Ds_Merchant_Terminal":        float64(1),
+			"Ds_Merchant_Currency":        float64(978),
+			"Ds_Merchant_TransactionType": float64(0),
+			"Ds_Merchant_Order":           "00011234abcd",
+			"Ds_Merchant_Amount":          float64(1000),
+		})
+		return map[string]interface{}{
+			"Ds_Order":             "00011234abcd",
+			"Ds_Response":          "0000",
+			"Ds_AuthorisationCode": "123456",
+			"Ds_Currency":          "978",
+		}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operation, err := client.Send(context.Background(), testMerchant(), Request{
+		Order:  "00011234abcd",
+		Amount: 1000,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, operation.Status, StatusApproved)
+	require.Equal(t, operation.Params.AuthCode, "123456")
+	require.Equal(t, operation.Params.Currency, CurrencyEuros)
+	require.True(t, operation.Sent.IsZero())
+}
+
+func TestClientSendSISError(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"errorCode": "SIS0051"}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	_, err := client.Send(context.Background(), testMerchant(), Request{Order: "00011234abcd", Amount: 1000})
+	var sisErr *SISError
+	require.True(t, errors.As(err, &sisErr))
+	require.Equal(t, sisErr.Code, "SIS0051")
+}
+
+func TestClientSendBadSignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewEncoder(w).Encode(restMessage{
+			SignatureVersion: "HMAC_SHA256_V1",
+			Params:           base64.StdEncoding.EncodeToString([]byte(`{"Ds_Order": "00011234abcd", "Ds_Response": "0000"}`)),
+			Signature:        "foobarqu",
+		}))
+	}))
+	defer server.Close()
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	_, err := client.Send(context.Background(), testMerchant(), Request{Order: "00011234abcd", Amount: 1000})
+	require.ErrorContains(t, err, "bad signature")
+}
+
+func TestClientSendMissingResponse(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"Ds_Order": "00011234abcd"}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	_, err := client.Send(context.Background(), testMerchant(), Request{Order: "00011234abcd", Amount: 1000})
+	require.EqualError(t, err, `missing response code of order "00011234abcd"`)
+}
+
+func TestClientSendUnexpectedOrder(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"Ds_Order": "00019999abcd", "Ds_Response": "0000"}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	_, err := client.Send(context.Background(), testMerchant(), Request{Order: "00011234abcd", Amount: 1000})
+	require.EqualError(t, err, `unexpected order "00019999abcd" in the response of order "00011234abcd"`)
+}
+
+func TestClientSendInvalidOrder(t *testing.T) {
+	client := new(Client)
+	_, err := client.Send(context.Background(), testMerchant(), Request{Order: "0001"})
+	require.EqualError(t, err, `invalid order format "0001"`)
+}
+
+func TestClientSendToken(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		require.Equal(t, params["Ds_Merchant_Identifier"], "b4ef9bfc3bd8e2a0bd2ad7aeb3c1b0d8b2a7e7d1")
+		require.Equal(t, params["Ds_Merchant_DirectPayment"], "true")
+		return map[string]interface{}{
+			"Ds_Order":    "00011234abcd",
+			"Ds_Response": "0000",
+		}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operation, err := client.Send(context.Background(), testMerchant(), Request{
+		Order:         "00011234abcd",
+		Amount:        1000,
+		Identifier:    "b4ef9bfc3bd8e2a0bd2ad7aeb3c1b0d8b2a7e7d1",
+		DirectPayment: true,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, operation.Status, StatusApproved)
+}
@@ -0,0 +1,82 @@
+package redsys
+
+import (
+	"fmt"
+)
+
+// COFType is the reason to store the card credentials for future payments.
+type COFType string
+
+const (
+	COFTypeInstallments    = COFType("I")
+	COFTypeRecurring       = COFType("R")
+	COFTypeReauthorization = COFType("H")
+	COFTypeResubmission    = COFType("E")
+	COFTypeDelayed         = COFType("D")
+	COFTypeIncremental     = COFType("M")
+	COFTypeNoShow          = COFType("N")
+	COFTypeOther           = COFType("C")
+)
+
+func (cofType COFType) valid() bool {
+	switch cofType {
+	case COFTypeInstallments, COFTypeRecurring, COFTypeReauthorization, COFTypeResubmission, COFTypeDelayed, COFTypeIncremental, COFTypeNoShow, COFTypeOther:
+		return true
+	}
+	return false
+}
+
+// CardOnFile configures payments with stored card credentials, like subscriptions or installments.
+type CardOnFile struct {
+	// Initial should be true in the first payment with the customer present, when the credentials are stored.
+	Initial bool
+
+	// Reason to store the credentials.
+	Type COFType
+
+	// Network transaction ID returned in the COFTxnID param of the initial payment. It is required in subsequent
+	// merchant initiated payments.
+	TxnID string
+
+	// MerchantInitiated marks subsequent payments started by the merchant without the customer. They will be exempt
+	// from the strong customer authentication with MIT.
+	MerchantInitiated bool
+}
+
+type cofRequest struct {
+	Ini      string  `json:"Ds_Merchant_Cof_Ini,omitempty"`
+	Type     COFType `json:"Ds_Merchant_Cof_Type,omitempty"`
+	TxnID    string  `json:"Ds_Merchant_Cof_Txnid,omitempty"`
+	ExcepSCA string  `json:"Ds_Merchant_Excep_SCA,omitempty"`
+}
+
+func (cof *CardOnFile) request() (*cofRequest, error) {
+	if cof == nil {
+		return nil, nil
+	}
+	if !cof.Type.valid() {
+		return nil, fmt.Errorf("unknown card on file type %q", cof.Type)
+	}
+
+	req := &cofRequest{
+		Ini:   "N",
+		Type:  cof.Type,
+		TxnID: cof.TxnID,
+	}
+	if cof.Initial {
+		if cof.TxnID != "" {
+			return nil, fmt.Errorf("initial card on file payment cannot have a transaction ID")
+		}
+		if cof.MerchantInitiated {
+			return nil, fmt.Errorf("initial card on file payment should be initiated by the customer")
+		}
+		req.Ini = "S"
+	}
+	if cof.MerchantInitiated {
+		if cof.TxnID == "" {
+			return nil, fmt.Errorf("merchant initiated payment requires the transaction ID of the initial payment")
+		}
+		req.ExcepSCA = "MIT"
+	}
+	return req, nil
+}
@@ -0,0 +1,129 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignInitialCardOnFile(t *testing.T) {
+	merchant := Merchant{
+		Secret: "sq7HjrUOBfKmC576ILgskD5srU870gJ7",
+	}
+	session := Session{
+		Order:      "00011234abcd",
+		Identifier: IdentifierRequired,
+		CardOnFile: &CardOnFile{
+			Initial: true,
+			Type:    COFTypeRecurring,
+		},
+	}
+	signed, err := Sign(context.Background(), merchant, session)
+	require.NoError(t, err)
+
+	decoded, err := base64.StdEncoding.DecodeString(signed.Params)
+	require.NoError(t, err)
+	params := map[string]interface{}{}
+	err = json.Unmarshal(decoded, &params)
+	require.NoError(t, err)
+
+	require.Equal(t, params["Ds_Merchant_Cof_Ini"], "S")
+	require.Equal(t, params["Ds_Merchant_Cof_Type"], "R")
+	require.NotContains(t, params, "Ds_Merchant_Cof_Txnid")
+	require.NotContains(t, params, "Ds_Merchant_Excep_SCA")
+}
+
+func TestSignMerchantInitiatedCardOnFile(t *testing.T) {
+	merchant := Merchant{
+		Secret: "sq7HjrUOBfKmC576ILgskD5srU870gJ7",
+	}
+	session := Session{
+		Order: "00011234abcd",
+		CardOnFile: &CardOnFile{
+			Type:              COFTypeRecurring,
+			TxnID:             "999999999999999",
+			MerchantInitiated: true,
+		},
+	}
+	_, err := Sign(context.Background(), merchant, session)
+	require.EqualError(t, err, "merchant initiated payments should be sent with the REST client")
+}
+
+func TestClientSendMerchantInitiatedCardOnFile(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		require.Equal(t, params["Ds_Merchant_Cof_Ini"], "N")
+		require.Equal(t, params["Ds_Merchant_Cof_Type"], "R")
+		require.Equal(t, params["Ds_Merchant_Cof_Txnid"], "999999999999999")
+		require.Equal(t, params["Ds_Merchant_Excep_SCA"], "MIT")
+		return map[string]interface{}{
+			"Ds_Order":    "00011234abcd",
+			"Ds_Response": "0000",
+		}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operation, err := client.Send(context.Background(), testMerchant(), Request{
+		Order:         "00011234abcd",
+		Amount:        1000,
+		Identifier:    "b4ef9bfc3bd8e2a0bd2ad7aeb3c1b0d8b2a7e7d1",
+		DirectPayment: true,
+		CardOnFile: &CardOnFile{
+			Type:              COFTypeRecurring,
+			TxnID:             "999999999999999",
+			MerchantInitiated: true,
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, operation.Status, StatusApproved)
+}
+
+func TestCardOnFileValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		cof  *CardOnFile
+		err  string
+	}{
+		{
+			name: "unknown type",
+			cof:  &CardOnFile{Initial: true, Type: "X"},
+			err:  `unknown card on file type "X"`,
+		},
+		{
+			name: "initial with transaction",
+			cof:  &CardOnFile{Initial: true, Type: COFTypeRecurring, TxnID: "999999999999999"},
+			err:  "initial card on file payment cannot have a transaction ID",
+		},
+		{
+			name: "initial merchant initiated",
+			cof:  &CardOnFile{Initial: true, Type: COFTypeRecurring, MerchantInitiated: true},
+			err:  "initial card on file payment should be initiated by the customer",
+		},
+		{
+			name: "merchant initiated without transaction",
+			cof:  &CardOnFile{Type: COFTypeInstallments, MerchantInitiated: true},
+			err:  "merchant initiated payment requires the transaction ID of the initial payment",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.cof.request()
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestParseParamsReadsCOFTxnID(t *testing.T) {
+	paramsEncoded := `{"Ds_Order": "order-code", "Ds_Merchant_Cof_Txnid": "999999999999999"}`
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           base64.StdEncoding.EncodeToString([]byte(paramsEncoded)),
+	}
+	params, err := ParseParams(signed)
+	require.NoError(t, err)
+
+	require.Equal(t, params.COFTxnID, "999999999999999")
+}
@@ -0,0 +1,28 @@
+package redsys
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurrency(t *testing.T) {
+	currency, err := ParseCurrency("gbp")
+	require.NoError(t, err)
+	require.Equal(t, currency, CurrencyPounds)
+
+	_, err = ParseCurrency("XAU")
+	require.EqualError(t, err, `unknown currency "XAU"`)
+}
+
+func TestCurrencyExponent(t *testing.T) {
+	require.Equal(t, CurrencyEuros.Exponent(), 2)
+	require.Equal(t, CurrencyYen.Exponent(), 0)
+	require.Equal(t, Currency(48).Exponent(), 3)
+}
+
+func TestCurrencyString(t *testing.T) {
+	require.Equal(t, CurrencyDollars.String(), "USD")
+	require.Equal(t, Currency(999).String(), "999")
+	require.False(t, Currency(999).Valid())
+}
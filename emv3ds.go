@@ -0,0 +1,317 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const pathInitiate = "/sis/rest/iniciaPeticionREST"
+
+// EMV3DS data returned by the bank in the Ds_EMV3DS param.
+type EMV3DS struct {
+	// Step of the authentication: CardConfiguration, ChallengeRequest, etc.
+	ThreeDSInfo string `json:"threeDSInfo"`
+
+	// Version of the 3-D Secure protocol, e.g. "2.2.0".
+	ProtocolVersion string `json:"protocolVersion"`
+
+	// Transaction ID assigned by the 3DS server.
+	ThreeDSServerTransID string `json:"threeDSServerTransID"`
+
+	// URL of the 3DS Method of the issuer. It will be empty if the issuer does not have one.
+	ThreeDSMethodURL string `json:"threeDSMethodURL"`
+
+	// URL of the issuer where the challenge should be sent.
+	AcsURL string `json:"acsURL"`
+
+	// Challenge request to send to the issuer.
+	CReq string `json:"creq"`
+
+	// Result of the authentication: Y authenticated, N not authenticated, U could not be performed, A attempted,
+	// C challenge required and R rejected.
+	TransStatus string `json:"transStatus"`
+
+	// Reason code of the issuer when the authentication was not successful.
+	TransStatusReason string `json:"transStatusReason"`
+
+	// Electronic Commerce Indicator of the authentication.
+	ECI string `json:"eci"`
+
+	// Message of the issuer explaining to the customer why the authentication failed.
+	CardholderInfo string `json:"cardholderInfo"`
+
+	// Raw data as received from the bank. It is kept when the bank sends a broken value that cannot be parsed.
+	Raw string `json:"-"`
+}
+
+// UnmarshalJSON reads the data both as an object and as a string with the JSON inside because the bank sends both
+// formats depending on the channel. Broken values are kept in Raw instead of failing because the data is only
+// informative and the signature of the whole params was already verified.
+func (emv3ds *EMV3DS) UnmarshalJSON(data []byte) error {
+	raw := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var inner string
+		if err := json.Unmarshal(data, &inner); err != nil {
+			return err
+		}
+		if strings.HasPrefix(strings.ToUpper(inner), "%7B") {
+			if unescaped, err := url.PathUnescape(inner); err == nil {
+				inner = unescaped
+			}
+		}
+		raw = inner
+		data = []byte(inner)
+	}
+
+	type plain EMV3DS
+	if err := json.Unmarshal(data, (*plain)(emv3ds)); err != nil {
+		*emv3ds = EMV3DS{Raw: raw}
+		return nil
+	}
+	emv3ds.Raw = raw
+	emv3ds.CardholderInfo = repairEncoding(emv3ds.CardholderInfo)
+	return nil
+}
+
+// repairEncoding fixes texts encoded twice, where each byte of the original UTF-8 text was read as a Latin-1
+// character. The bank sends some messages of the issuers this way.
+func repairEncoding(s string) string {
+	var b []byte
+	var latin bool
+	for _, r := range s {
+		if r > 0xff {
+			return s
+		}
+		if r >= 0x80 {
+			latin = true
+		}
+		b = append(b, byte(r))
+	}
+	if !latin || !utf8.Valid(b) {
+		return s
+	}
+	return string(b)
+}
+
+type emv3dsRequest struct {
+	ThreeDSInfo              string `json:"threeDSInfo,omitempty"`
+	ProtocolVersion          string `json:"protocolVersion,omitempty"`
+	BrowserAcceptHeader      string `json:"browserAcceptHeader,omitempty"`
+	BrowserColorDepth        string `json:"browserColorDepth,omitempty"`
+	BrowserIP                string `json:"browserIP,omitempty"`
+	BrowserJavaEnabled       string `json:"browserJavaEnabled,omitempty"`
+	BrowserJavascriptEnabled string `json:"browserJavascriptEnabled,omitempty"`
+	BrowserLanguage          string `json:"browserLanguage,omitempty"`
+	BrowserScreenHeight      string `json:"browserScreenHeight,omitempty"`
+	BrowserScreenWidth       string `json:"browserScreenWidth,omitempty"`
+	BrowserTZ                string `json:"browserTZ,omitempty"`
+	BrowserUserAgent         string `json:"browserUserAgent,omitempty"`
+	ThreeDSServerTransID     string `json:"threeDSServerTransID,omitempty"`
+	NotificationURL          string `json:"notificationURL,omitempty"`
+	ThreeDSCompInd           string `json:"threeDSCompInd,omitempty"`
+	CRes                     string `json:"cres,omitempty"`
+	*cardholderRequest
+}
+
+// ThreeDSCompletion reports if the 3DS Method of the issuer finished in the browser.
+type ThreeDSCompletion string
+
+const (
+	// ThreeDSMethodCompleted means the 3DS Method notified its completion.
+	ThreeDSMethodCompleted = ThreeDSCompletion("Y")
+
+	// ThreeDSMethodNotCompleted means the 3DS Method did not notify its completion in 10 seconds.
+	ThreeDSMethodNotCompleted = ThreeDSCompletion("N")
+
+	// ThreeDSMethodUnavailable means the issuer does not have a 3DS Method.
+	ThreeDSMethodUnavailable = ThreeDSCompletion("U")
+)
+
+// Browser of the customer collected in the checkout page. It is required by the issuer to evaluate the risk.
+type Browser struct {
+	// Value of the Accept header of the customer request.
+	AcceptHeader string
+
+	// Value of the User-Agent header of the customer request.
+	UserAgent string
+
+	// IP address of the customer.
+	IP string
+
+	// Language of the browser from navigator.language.
+	Language string
+
+	// Value of navigator.javaEnabled().
+	JavaEnabled bool
+
+	// True if the data was collected with Javascript.
+	JavascriptEnabled bool
+
+	// Value of screen.colorDepth.
+	ColorDepth int
+
+	// Value of screen.height.
+	ScreenHeight int
+
+	// Value of screen.width.
+	ScreenWidth int
+
+	// Value of new Date().getTimezoneOffset().
+	TZ int
+}
+
+// ThreeDSMethod should be run in a hidden iframe of the browser sending a POST form to URL with the field
+// threeDSMethodData. The issuer will notify the completion to the notification URL of the method.
+type ThreeDSMethod struct {
+	URL  string
+	Data string
+}
+
+// ThreeDSChallenge should be run in the browser sending a POST form to AcsURL with the field creq. The issuer
+// will send the result to the notification URL in the field cres.
+type ThreeDSChallenge struct {
+	AcsURL string
+	CReq   string
+}
+
+// ThreeDSResult is the result of the authentication data step.
+type ThreeDSResult struct {
+	// Challenge to run in the browser. It will be nil if the issuer authenticated the payment without friction.
+	Challenge *ThreeDSChallenge
+
+	// Final operation if there is no challenge.
+	Operation Operation
+}
+
+// ThreeDSFlow drives the EMV3DS authentication of a direct integration payment step by step. The flow spans
+// several requests of the customer and can be stored between them as JSON. The card number, expiry date and
+// security code are never stored; assign them again to the Request of the restored flow before the next step.
+//
+// The steps are:
+//  1. Start to get the card configuration and the 3DS Method of the issuer, if any.
+//  2. Run the 3DS Method in a hidden iframe.
+//  3. Authenticate with the browser data to obtain the final operation or a challenge.
+//  4. Run the challenge in the browser and finish with CompleteChallenge when the result arrives.
+type ThreeDSFlow struct {
+	// Client to send the requests.
+	Client *Client `json:"-"`
+
+	// Merchant of the payment.
+	Merchant Merchant `json:"-"`
+
+	// Payment to authenticate. It should contain the card data or a token.
+	Request Request
+
+	// Protocol version returned by the bank when starting the flow.
+	ProtocolVersion string
+
+	// Transaction ID of the 3DS server returned by the bank when starting the flow.
+	ThreeDSServerTransID string
+}
+
+// MarshalJSON implements json.Marshaler removing the card data of the request, which cannot be stored.
+func (flow ThreeDSFlow) MarshalJSON() ([]byte, error) {
+	type storedFlow ThreeDSFlow
+	flow.Request.Pan = ""
+	flow.Request.ExpiryDate = ""
+	flow.Request.CVV2 = ""
+	return json.Marshal(storedFlow(flow))
+}
+
+// Start asks the bank for the card configuration. It returns the 3DS Method that should be run before the
+// authentication, or nil if the issuer does not have one.
+func (flow *ThreeDSFlow) Start(ctx context.Context, methodNotificationURL string) (*ThreeDSMethod, error) {
+	params, err := flow.Client.do(ctx, flow.Merchant, pathInitiate, flow.Request, &emv3dsRequest{
+		ThreeDSInfo: "CardData",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if params.EMV3DS == nil || params.EMV3DS.ThreeDSInfo != "CardConfiguration" {
+		return nil, fmt.Errorf("missing card configuration of order %q", params.Order)
+	}
+	if !strings.HasPrefix(params.EMV3DS.ProtocolVersion, "2.") {
+		return nil, fmt.Errorf("unsupported 3DS protocol version %q", params.EMV3DS.ProtocolVersion)
+	}
+	flow.ProtocolVersion = params.EMV3DS.ProtocolVersion
+	flow.ThreeDSServerTransID = params.EMV3DS.ThreeDSServerTransID
+
+	if params.EMV3DS.ThreeDSMethodURL == "" {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]string{
+		"threeDSServerTransID":         flow.ThreeDSServerTransID,
+		"threeDSMethodNotificationURL": methodNotificationURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal method data: %v", err)
+	}
+	method := &ThreeDSMethod{
+		URL:  params.EMV3DS.ThreeDSMethodURL,
+		Data: base64.RawURLEncoding.EncodeToString(data),
+	}
+	return method, nil
+}
+
+// Authenticate sends the authentication data of the customer. The notification URL will receive the result of
+// the challenge if the issuer requires one.
+func (flow *ThreeDSFlow) Authenticate(ctx context.Context, browser Browser, completion ThreeDSCompletion, notificationURL string) (ThreeDSResult, error) {
+	if flow.ProtocolVersion == "" {
+		return ThreeDSResult{}, fmt.Errorf("3DS flow of order %q not started", flow.Request.Order)
+	}
+	params, err := flow.Client.do(ctx, flow.Merchant, pathREST, flow.Request, &emv3dsRequest{
+		ThreeDSInfo:              "AuthenticationData",
+		ProtocolVersion:          flow.ProtocolVersion,
+		BrowserAcceptHeader:      browser.AcceptHeader,
+		BrowserColorDepth:        strconv.Itoa(browser.ColorDepth),
+		BrowserIP:                browser.IP,
+		BrowserJavaEnabled:       strconv.FormatBool(browser.JavaEnabled),
+		BrowserJavascriptEnabled: strconv.FormatBool(browser.JavascriptEnabled),
+		BrowserLanguage:          browser.Language,
+		BrowserScreenHeight:      strconv.Itoa(browser.ScreenHeight),
+		BrowserScreenWidth:       strconv.Itoa(browser.ScreenWidth),
+		BrowserTZ:                strconv.Itoa(browser.TZ),
+		BrowserUserAgent:         browser.UserAgent,
+		ThreeDSServerTransID:     flow.ThreeDSServerTransID,
+		NotificationURL:          notificationURL,
+		ThreeDSCompInd:           string(completion),
+	})
+	if err != nil {
+		return ThreeDSResult{}, err
+	}
+	if params.EMV3DS != nil && params.EMV3DS.ThreeDSInfo == "ChallengeRequest" {
+		challenge := &ThreeDSChallenge{
+			AcsURL: params.EMV3DS.AcsURL,
+			CReq:   params.EMV3DS.CReq,
+		}
+		return ThreeDSResult{Challenge: challenge}, nil
+	}
+
+	operation, err := newServerOperation(params)
+	if err != nil {
+		return ThreeDSResult{}, err
+	}
+	return ThreeDSResult{Operation: operation}, nil
+}
+
+// CompleteChallenge sends the cres field received in the notification URL and returns the final operation.
+func (flow *ThreeDSFlow) CompleteChallenge(ctx context.Context, cres string) (Operation, error) {
+	if flow.ProtocolVersion == "" {
+		return Operation{}, fmt.Errorf("3DS flow of order %q not started", flow.Request.Order)
+	}
+	params, err := flow.Client.do(ctx, flow.Merchant, pathREST, flow.Request, &emv3dsRequest{
+		ThreeDSInfo:     "ChallengeResponse",
+		ProtocolVersion: flow.ProtocolVersion,
+		CRes:            cres,
+	})
+	if err != nil {
+		return Operation{}, err
+	}
+	return newServerOperation(params)
+}
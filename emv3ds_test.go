@@ -0,0 +1,278 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testBrowser() Browser {
+	return Browser{
+		AcceptHeader:      "text/html",
+		UserAgent:         "Mozilla/5.0",
+		IP:                "127.0.0.1",
+		Language:          "es-ES",
+		JavascriptEnabled: true,
+		ColorDepth:        24,
+		ScreenHeight:      1080,
+		ScreenWidth:       1920,
+		TZ:                -60,
+	}
+}
+
+func TestThreeDSFlowFrictionless(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		emv3ds := params["Ds_Merchant_EMV3DS"].(map[string]interface{})
+		require.Equal(t, params["Ds_Merchant_Pan"], "4548810000000003")
+
+		switch path {
+		case "/sis/rest/iniciaPeticionREST":
+			require.Equal(t, emv3ds, map[string]interface{}{"threeDSInfo": "CardData"})
+			return map[string]interface{}{
+				"Ds_Order": "00011234abcd",
+				"Ds_EMV3DS": map[string]interface{}{
+					"protocolVersion":      "2.1.0",
+					"threeDSServerTransID": "8de84430-3336-4ff4-b18d-f073b546ccea",
+					"threeDSInfo":          "CardConfiguration",
+					"threeDSMethodURL":     "https://acs.example.com/method",
+				},
+			}
+
+		case "/sis/rest/trataPeticionREST":
+			require.Equal(t, emv3ds["threeDSInfo"], "AuthenticationData")
+			require.Equal(t, emv3ds["protocolVersion"], "2.1.0")
+			require.Equal(t, emv3ds["threeDSServerTransID"], "8de84430-3336-4ff4-b18d-f073b546ccea")
+			require.Equal(t, emv3ds["threeDSCompInd"], "Y")
+			require.Equal(t, emv3ds["browserJavascriptEnabled"], "true")
+			require.Equal(t, emv3ds["browserTZ"], "-60")
+			require.Equal(t, emv3ds["notificationURL"], "https://www.example.com/challenge")
+			return map[string]interface{}{
+				"Ds_Order":    "00011234abcd",
+				"Ds_Response": "0000",
+			}
+		}
+		t.Fatalf("unexpected path %q", path)
+		return nil
+	})
+	flow := &ThreeDSFlow{
+		Client:   &Client{HTTPClient: server.Client(), BaseURL: server.URL},
+		Merchant: testMerchant(),
+		Request: Request{
+			Order:      "00011234abcd",
+			Amount:     1000,
+			Pan:        "4548810000000003",
+			ExpiryDate: "4912",
+			CVV2:       "123",
+		},
+	}
+
+	method, err := flow.Start(context.Background(), "https://www.example.com/method")
+	require.NoError(t, err)
+	require.Equal(t, method.URL, "https://acs.example.com/method")
+	data, err := base64.RawURLEncoding.DecodeString(method.Data)
+	require.NoError(t, err)
+	require.JSONEq(t, string(data), `{"threeDSServerTransID": "8de84430-3336-4ff4-b18d-f073b546ccea", "threeDSMethodNotificationURL": "https://www.example.com/method"}`)
+
+	result, err := flow.Authenticate(context.Background(), testBrowser(), ThreeDSMethodCompleted, "https://www.example.com/challenge")
+	require.NoError(t, err)
+	require.Nil(t, result.Challenge)
+	require.Equal(t, result.Operation.Status, StatusApproved)
+}
+
+func TestThreeDSFlowChallenge(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		emv3ds := params["Ds_Merchant_EMV3DS"].(map[string]interface{})
+		switch emv3ds["threeDSInfo"] {
+		case "CardData":
+			return map[string]interface{}{
+				"Ds_Order": "00011234abcd",
+				"Ds_EMV3DS": map[string]interface{}{
+					"protocolVersion":      "2.2.0",
+					"threeDSServerTransID": "8de84430-3336-4ff4-b18d-f073b546ccea",
+					"threeDSInfo":          "CardConfiguration",
+				},
+			}
+
+		case "AuthenticationData":
+			require.Equal(t, emv3ds["threeDSCompInd"], "U")
+			return map[string]interface{}{
+				"Ds_Order": "00011234abcd",
+				"Ds_EMV3DS": map[string]interface{}{
+					"protocolVersion": "2.2.0",
+					"threeDSInfo":     "ChallengeRequest",
+					"acsURL":          "https://acs.example.com/challenge",
+					"creq":            "eyJ0aHJlZURTU2VydmVyVHJhbnNJRCI6IjhkZTg0NDMwIn0",
+				},
+			}
+
+		case "ChallengeResponse":
+			require.Equal(t, emv3ds["protocolVersion"], "2.2.0")
+			require.Equal(t, emv3ds["cres"], "eyJ0cmFuc1N0YXR1cyI6IlkifQ")
+			return map[string]interface{}{
+				"Ds_Order":    "00011234abcd",
+				"Ds_Response": "0000",
+			}
+		}
+		t.Fatalf("unexpected step %q", emv3ds["threeDSInfo"])
+		return nil
+	})
+	flow := &ThreeDSFlow{
+		Client:   &Client{HTTPClient: server.Client(), BaseURL: server.URL},
+		Merchant: testMerchant(),
+		Request: Request{
+			Order:      "00011234abcd",
+			Amount:     1000,
+			Identifier: "b4ef9bfc3bd8e2a0bd2ad7aeb3c1b0d8b2a7e7d1",
+		},
+	}
+
+	method, err := flow.Start(context.Background(), "https://www.example.com/method")
+	require.NoError(t, err)
+	require.Nil(t, method)
+
+	result, err := flow.Authenticate(context.Background(), testBrowser(), ThreeDSMethodUnavailable, "https://www.example.com/challenge")
+	require.NoError(t, err)
+	require.Equal(t, result.Challenge, &ThreeDSChallenge{
+		AcsURL: "https://acs.example.com/challenge",
+		CReq:   "eyJ0aHJlZURTU2VydmVyVHJhbnNJRCI6IjhkZTg0NDMwIn0",
+	})
+
+	// The flow is stored while the customer completes the challenge.
+	stored, err := json.Marshal(flow)
+	require.NoError(t, err)
+	restored := &ThreeDSFlow{
+		Client:   flow.Client,
+		Merchant: testMerchant(),
+	}
+	require.NoError(t, json.Unmarshal(stored, restored))
+
+	operation, err := restored.CompleteChallenge(context.Background(), "eyJ0cmFuc1N0YXR1cyI6IlkifQ")
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusApproved)
+}
+
+func TestThreeDSFlowStoredWithoutCardData(t *testing.T) {
+	flow := &ThreeDSFlow{
+		Request: Request{
+			Order:      "00011234abcd",
+			Amount:     1000,
+			Pan:        "4548810000000003",
+			ExpiryDate: "4912",
+			CVV2:       "123",
+		},
+		ProtocolVersion: "2.2.0",
+	}
+	stored, err := json.Marshal(flow)
+	require.NoError(t, err)
+	require.NotContains(t, string(stored), "4548810000000003")
+	require.NotContains(t, string(stored), "4912")
+	require.NotContains(t, string(stored), `"123"`)
+
+	restored := new(ThreeDSFlow)
+	require.NoError(t, json.Unmarshal(stored, restored))
+	require.Equal(t, restored.Request.Order, "00011234abcd")
+	require.Equal(t, restored.ProtocolVersion, "2.2.0")
+	require.Empty(t, restored.Request.Pan)
+
+	require.Equal(t, flow.Request.CVV2, "123")
+}
+
+func TestThreeDSFlowUnsupportedVersion(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"Ds_Order": "00011234abcd",
+			"Ds_EMV3DS": map[string]interface{}{
+				"protocolVersion": "1.0.2",
+				"threeDSInfo":     "CardConfiguration",
+			},
+		}
+	})
+	flow := &ThreeDSFlow{
+		Client:   &Client{HTTPClient: server.Client(), BaseURL: server.URL},
+		Merchant: testMerchant(),
+		Request:  Request{Order: "00011234abcd", Amount: 1000},
+	}
+
+	_, err := flow.Start(context.Background(), "https://www.example.com/method")
+	require.EqualError(t, err, `unsupported 3DS protocol version "1.0.2"`)
+}
+
+func TestThreeDSFlowNotStarted(t *testing.T) {
+	flow := &ThreeDSFlow{
+		Client:  new(Client),
+		Request: Request{Order: "00011234abcd", Amount: 1000},
+	}
+	_, err := flow.Authenticate(context.Background(), testBrowser(), ThreeDSMethodCompleted, "https://www.example.com/challenge")
+	require.EqualError(t, err, `3DS flow of order "00011234abcd" not started`)
+}
+
+func TestEMV3DSUnmarshalString(t *testing.T) {
+	var emv3ds EMV3DS
+	err := json.Unmarshal([]byte(`"{\"protocolVersion\":\"2.1.0\",\"threeDSInfo\":\"CardConfiguration\"}"`), &emv3ds)
+	require.NoError(t, err)
+
+	require.Equal(t, emv3ds.ProtocolVersion, "2.1.0")
+	require.Equal(t, emv3ds.ThreeDSInfo, "CardConfiguration")
+}
+
+func TestParseParamsReadsEMV3DS(t *testing.T) {
+	paramsEncoded := `{"Ds_Order": "order-code", "Ds_Response": "0184", "Ds_EMV3DS": {"protocolVersion": "2.2.0", "threeDSInfo": "AuthenticationData", "transStatus": "N", "transStatusReason": "01", "eci": "07", "cardholderInfo": "Tarjeta bloqueada"}}`
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           base64.StdEncoding.EncodeToString([]byte(paramsEncoded)),
+	}
+	params, err := ParseParams(signed)
+	require.NoError(t, err)
+
+	require.Equal(t, params.EMV3DS.ProtocolVersion, "2.2.0")
+	require.Equal(t, params.EMV3DS.TransStatus, "N")
+	require.Equal(t, params.EMV3DS.TransStatusReason, "01")
+	require.Equal(t, params.EMV3DS.ECI, "07")
+	require.Equal(t, params.EMV3DS.CardholderInfo, "Tarjeta bloqueada")
+}
+
+func TestParseParamsRepairsEMV3DSEncoding(t *testing.T) {
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           "eyJEc19EYXRlIjoiMDElMkYwMiUyRjIwMjUiLCJEc19Ib3VyIjoiMDklM0EyMiIsIkRzX1NlY3VyZVBheW1lbnQiOiIwIiwiRHNfQW1vdW50IjoiMjY1ODgiLCJEc19DdXJyZW5jeSI6Ijk3OCIsIkRzX09yZGVyIjoiMDAwMDI0OGQ2MjA2IiwiRHNfTWVyY2hhbnRDb2RlIjoiNjY0NTI4NjMiLCJEc19UZXJtaW5hbCI6IjAwMSIsIkRzX1Jlc3BvbnNlIjoiOTYwMSIsIkRzX1RyYW5zYWN0aW9uVHlwZSI6IjAiLCJEc19NZXJjaGFudERhdGEiOiJwcm9qZWN0cyUyRmdhcmElMkZzZXNzaW9ucyUyRmNmMWQ3NzhmLTcwOWMtNDlkOC1hOTU2LWI0ODVhOTJmMWY4MSUyRm9yZGVycyUyRjAwMDAyNDhkNjIwNiIsIkRzX0F1dGhvcmlzYXRpb25Db2RlIjoiKysrKysrIiwiRHNfQ29uc3VtZXJMYW5ndWFnZSI6IjEiLCJEc19DYXJkX0NvdW50cnkiOiI3MjQiLCJEc19FTVYzRFMiOnsiY2FyZGhvbGRlckluZm8iOiJDb25zdWx0ZSBjb24gbGEgZW50aWRhZCBiYW5jYXJpYSBzaSBzdSB0YXJqZXRhIGVzdMOvwr_CvSBhY3RpdmFkYSBwYXJhIHJlYWxpemFyIG9wZXJhY2lvbmVzIGNvbiBhdXRlbnRpY2FjacOvwr_CvW4uIn19",
+	}
+	params, err := ParseParams(signed)
+	require.NoError(t, err)
+
+	require.Equal(t, params.EMV3DS.CardholderInfo, "Consulte con la entidad bancaria si su tarjeta est� activada para realizar operaciones con autenticaci�n.")
+}
+
+func TestParseParamsEscapedEMV3DS(t *testing.T) {
+	paramsEncoded := `{"Ds_Order": "order-code", "Ds_EMV3DS": "%7B%22transStatus%22%3A%22Y%22%2C%22eci%22%3A%2205%22%7D"}`
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           base64.StdEncoding.EncodeToString([]byte(paramsEncoded)),
+	}
+	params, err := ParseParams(signed)
+	require.NoError(t, err)
+
+	require.Equal(t, params.EMV3DS.TransStatus, "Y")
+	require.Equal(t, params.EMV3DS.ECI, "05")
+}
+
+func TestParseParamsBrokenEMV3DS(t *testing.T) {
+	paramsEncoded := `{"Ds_Order": "order-code", "Ds_Response": "0000", "Ds_EMV3DS": "{\"transStatus\":"}`
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           base64.StdEncoding.EncodeToString([]byte(paramsEncoded)),
+	}
+	params, err := ParseParams(signed)
+	require.NoError(t, err)
+
+	require.Empty(t, params.EMV3DS.TransStatus)
+	require.Equal(t, params.EMV3DS.Raw, `{"transStatus":`)
+}
+
+func TestRepairEncoding(t *testing.T) {
+	require.Equal(t, repairEncoding("autenticaciÃ³n"), "autenticación")
+	require.Equal(t, repairEncoding("autenticación"), "autenticación")
+	require.Equal(t, repairEncoding("está activada"), "está activada")
+}
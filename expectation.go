@@ -0,0 +1,107 @@
+package redsys
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expectation of the values of a confirmed transaction. Empty fields are not checked.
+type Expectation struct {
+	// Amount originally signed in the minor unit of the currency.
+	Amount int32
+
+	// Currency of the terminal.
+	Currency Currency
+
+	// Merchant code assigned by the bank.
+	MerchantCode string
+
+	// Terminal number assigned by the bank.
+	Terminal int64
+}
+
+// ExpectationFor returns the expected values of the confirmation of a signed session.
+func ExpectationFor(merchant Merchant, session Session) Expectation {
+	return Expectation{
+		Amount:       session.Amount,
+		Currency:     merchant.currencyOrDefault(),
+		MerchantCode: merchant.Code,
+		Terminal:     merchant.Terminal,
+	}
+}
+
+// FieldMismatch is a field of the confirmation that does not have the expected value.
+type FieldMismatch struct {
+	// Name of the parameter, e.g. Ds_Amount.
+	Field string
+
+	// Expected value.
+	Expected string
+
+	// Value received from the bank.
+	Got string
+}
+
+// MismatchError is returned when a correctly signed confirmation does not have the expected values.
+type MismatchError struct {
+	Fields []FieldMismatch
+}
+
+func (err *MismatchError) Error() string {
+	var fields []string
+	for _, field := range err.Fields {
+		fields = append(fields, fmt.Sprintf("%s expected %q got %q", field.Field, field.Expected, field.Got))
+	}
+	return fmt.Sprintf("unexpected confirmation values: %s", strings.Join(fields, ", "))
+}
+
+// ConfirmExpected works like Confirm but also checks that the transaction has the expected values. If they do not
+// match a *MismatchError is returned with every field that differs.
+func ConfirmExpected(ctx context.Context, secret string, signed Signed, expectation Expectation) (Operation, error) {
+	operation, err := Confirm(ctx, secret, signed)
+	if err != nil {
+		return Operation{}, err
+	}
+	if err := expectation.check(operation.Params); err != nil {
+		return Operation{}, err
+	}
+	return operation, nil
+}
+
+func (expectation Expectation) check(params Params) error {
+	var fields []FieldMismatch
+	if expectation.Amount != 0 && (params.RawAmount == "" || params.Amount != expectation.Amount) {
+		fields = append(fields, FieldMismatch{
+			Field:    "Ds_Amount",
+			Expected: strconv.FormatInt(int64(expectation.Amount), 10),
+			Got:      params.RawAmount,
+		})
+	}
+	if expectation.Currency != 0 && (params.RawCurrency == "" || params.Currency != expectation.Currency) {
+		fields = append(fields, FieldMismatch{
+			Field:    "Ds_Currency",
+			Expected: strconv.FormatInt(int64(expectation.Currency), 10),
+			Got:      params.RawCurrency,
+		})
+	}
+	if expectation.MerchantCode != "" && params.MerchantCode != expectation.MerchantCode {
+		fields = append(fields, FieldMismatch{
+			Field:    "Ds_MerchantCode",
+			Expected: expectation.MerchantCode,
+			Got:      params.MerchantCode,
+		})
+	}
+	if expectation.Terminal != 0 && (params.RawTerminal == "" || params.Terminal != expectation.Terminal) {
+		fields = append(fields, FieldMismatch{
+			Field:    "Ds_Terminal",
+			Expected: strconv.FormatInt(expectation.Terminal, 10),
+			Got:      params.RawTerminal,
+		})
+	}
+	if len(fields) > 0 {
+		return &MismatchError{Fields: fields}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package redsys
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmExpected(t *testing.T) {
+	signed := signNotification(t, map[string]string{
+		"Ds_Order":        "00011234abcd",
+		"Ds_Response":     "0000",
+		"Ds_Amount":       "1000",
+		"Ds_Currency":     "978",
+		"Ds_MerchantCode": "123456789",
+		"Ds_Terminal":     "001",
+	})
+	expectation := ExpectationFor(testMerchant(), Session{Order: "00011234abcd", Amount: 1000})
+
+	operation, err := ConfirmExpected(context.Background(), testSecret, signed, expectation)
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusApproved)
+}
+
+func TestConfirmExpectedMismatch(t *testing.T) {
+	signed := signNotification(t, map[string]string{
+		"Ds_Order":        "00011234abcd",
+		"Ds_Response":     "0000",
+		"Ds_Amount":       "100",
+		"Ds_Currency":     "840",
+		"Ds_MerchantCode": "123456789",
+	})
+	expectation := ExpectationFor(testMerchant(), Session{Order: "00011234abcd", Amount: 1000})
+
+	_, err := ConfirmExpected(context.Background(), testSecret, signed, expectation)
+	var mismatch *MismatchError
+	require.True(t, errors.As(err, &mismatch))
+	require.Equal(t, mismatch.Fields, []FieldMismatch{
+		{Field: "Ds_Amount", Expected: "1000", Got: "100"},
+		{Field: "Ds_Currency", Expected: "978", Got: "840"},
+		{Field: "Ds_Terminal", Expected: "1", Got: ""},
+	})
+	require.EqualError(t, err, `unexpected confirmation values: Ds_Amount expected "1000" got "100", Ds_Currency expected "978" got "840", Ds_Terminal expected "1" got ""`)
+}
+
+func TestConfirmExpectedSkipsEmptyFields(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_Amount": "100"})
+
+	_, err := ConfirmExpected(context.Background(), testSecret, signed, Expectation{})
+	require.NoError(t, err)
+}
+
+func TestConfirmExpectedBadSignature(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_Amount": "1000"})
+	signed.Signature = "foobarqu"
+
+	_, err := ConfirmExpected(context.Background(), testSecret, signed, Expectation{Amount: 1000})
+	require.ErrorContains(t, err, "bad signature")
+}
@@ -0,0 +1,231 @@
+package redsys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Handler receives the background notifications of the bank, verifies them and calls the callback of the
+// resulting status. It accepts both POST forms and GET query strings.
+//
+// The handler replies with a 400 status to notifications that cannot be verified and with a 500 status when the
+// callback fails, so the bank retries the notification later. The replies never include the reason of the error,
+// it is only written to the ErrorLog.
+type Handler struct {
+	// Secret to verify the notifications assigned by the bank.
+	Secret string
+
+	// Keys to verify the notifications during a rotation of the secret. If configured Secret is ignored.
+	Keys KeySet
+
+	// Resolver of the secret for applications with multiple merchants. If configured Secret and Keys are ignored.
+	Resolver SecretResolver
+
+	// OnApproved is called for approved operations.
+	OnApproved OperationFunc
+
+	// OnCancelled is called for cancelled operations.
+	OnCancelled OperationFunc
+
+	// OnRepeated is called for operations sent repeatedly to the bank.
+	OnRepeated OperationFunc
+
+	// OnUnknown is called for operations whose status cannot be detected.
+	OnUnknown OperationFunc
+
+	// OnDenied is called for operations denied by the issuer. If empty the callback of Status is called instead.
+	OnDenied OperationFunc
+
+	// OnFraud is called for operations blocked under suspicion of fraud. If empty the callback of Status is
+	// called instead.
+	OnFraud OperationFunc
+
+	// OnError is called for operations that failed because of a technical error. If empty the callback of Status
+	// is called instead.
+	OnError OperationFunc
+
+	// OnPending is called for operations that have not finished yet. If empty the callback of Status is called
+	// instead.
+	OnPending OperationFunc
+
+	// Classifier of the status of the operations. By default it will be the same classification of Confirm.
+	Classifier Classifier
+
+	// Store of the processed notifications. If configured the callbacks run only once for each distinct
+	// notification, even if the bank sends it again, the user comes back with it to the URLOK page or a Reconciler
+	// already emitted the same result of the order.
+	Store Store
+
+	// ErrorLog receives the reasons of the rejected and failed notifications. By default it will be the standard
+	// logger of the log package.
+	ErrorLog *log.Logger
+}
+
+// ServeHTTP implements http.Handler.
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		handler.logf("cannot parse notification: %v", err)
+		http.Error(w, "cannot parse notification", http.StatusBadRequest)
+		return
+	}
+
+	signed := Signed{
+		SignatureVersion: r.Form.Get("Ds_SignatureVersion"),
+		Params:           r.Form.Get("Ds_MerchantParameters"),
+		Signature:        r.Form.Get("Ds_Signature"),
+	}
+	if signed.SignatureVersion == "" || signed.Params == "" || signed.Signature == "" {
+		http.Error(w, "incomplete notification", http.StatusBadRequest)
+		return
+	}
+
+	operation, status, err := handler.confirm(r.Context(), signed)
+	if err != nil {
+		// The error may contain details of the verification that should not be disclosed to the sender.
+		handler.logf("invalid notification: %v", err)
+		http.Error(w, "invalid notification", status)
+		return
+	}
+
+	if err := handler.Dispatch(r.Context(), operation); err != nil {
+		handler.logf("cannot process notification of order %q: %v", operation.Params.Order, err)
+		http.Error(w, "cannot process notification", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (handler *Handler) logf(format string, args ...interface{}) {
+	if handler.ErrorLog != nil {
+		handler.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Process verifies the parameters and calls the callback of the resulting status, only once if a Store is
+// configured. It can be used in the URLOK page to share the processing with the background notification.
+func (handler *Handler) Process(ctx context.Context, signed Signed) (Operation, error) {
+	operation, _, err := handler.confirm(ctx, signed)
+	if err != nil {
+		return Operation{}, err
+	}
+	if err := handler.Dispatch(ctx, operation); err != nil {
+		return Operation{}, err
+	}
+	return operation, nil
+}
+
+// confirm verifies the notification with the keys of the handler or the secret of the resolver. It also returns the HTTP
+// status to reply if it fails.
+func (handler *Handler) confirm(ctx context.Context, signed Signed) (Operation, int, error) {
+	keys := handler.Keys
+	if len(keys) == 0 {
+		keys = KeySet{{Secret: handler.Secret}}
+	}
+	if handler.Resolver != nil {
+		params, err := ParseParams(signed)
+		if err != nil {
+			return Operation{}, http.StatusBadRequest, fmt.Errorf("cannot parse params: %v", err)
+		}
+		secret, err := resolveSecret(ctx, handler.Resolver, params)
+		if err != nil {
+			if errors.Is(err, ErrUnknownMerchant) {
+				return Operation{}, http.StatusBadRequest, err
+			}
+			// The resolver may fail temporarily, let the bank retry the notification.
+			return Operation{}, http.StatusInternalServerError, err
+		}
+		keys = KeySet{{Secret: secret}}
+	}
+
+	operation, err := ConfirmKeys(ctx, keys, signed)
+	if err != nil {
+		return Operation{}, http.StatusBadRequest, err
+	}
+	if handler.Classifier != nil {
+		operation = operation.Reclassify(handler.Classifier)
+	}
+	return operation, http.StatusOK, nil
+}
+
+// Dispatch calls the callback of the outcome of a verified operation, or the callback of its status if there is
+// none for the outcome. The operation is classified again if the handler has a Classifier. Missing callbacks are
+// ignored. If a Store is configured the callback runs only once for each order and response, the same as in the
+// notifications. It can be used as the Notify function of a Reconciler so a notification that arrives after the
+// reconciliation of its order is not processed again.
+func (handler *Handler) Dispatch(ctx context.Context, operation Operation) error {
+	if handler.Classifier != nil {
+		operation = operation.Reclassify(handler.Classifier)
+	}
+	if handler.Store == nil {
+		return handler.dispatch(ctx, operation)
+	}
+
+	key := notificationKey(operation)
+	claimed, err := handler.Store.Claim(ctx, key)
+	if err != nil {
+		return fmt.Errorf("cannot claim notification %q: %w", key, err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	if err := handler.dispatch(ctx, operation); err != nil {
+		// Forget the notification so the retry of the bank can process it again.
+		if releaseErr := handler.Store.Release(ctx, key); releaseErr != nil {
+			return fmt.Errorf("cannot release notification %q: %v: %w", key, releaseErr, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// notificationKey identifies a distinct result of an order by its transaction type and response. It does not
+// depend on the signature so the reconciled operations, that are not signed, share the key of their notification.
+func notificationKey(operation Operation) string {
+	var response string
+	if operation.Params.RawResponse != "" {
+		response = strconv.FormatInt(operation.Params.Response, 10)
+	}
+	return fmt.Sprintf("%s/%d/%s", operation.Params.Order, operation.Params.TransactionType, response)
+}
+
+func (handler *Handler) dispatch(ctx context.Context, operation Operation) error {
+	var fn OperationFunc
+	switch operation.Outcome {
+	case StatusDenied:
+		fn = handler.OnDenied
+	case StatusFraud:
+		fn = handler.OnFraud
+	case StatusError:
+		fn = handler.OnError
+	case StatusPending:
+		fn = handler.OnPending
+	}
+	if fn == nil {
+		switch operation.Status {
+		case StatusApproved:
+			fn = handler.OnApproved
+		case StatusCancelled:
+			fn = handler.OnCancelled
+		case StatusRepeated:
+			fn = handler.OnRepeated
+		default:
+			fn = handler.OnUnknown
+		}
+	}
+	if fn == nil {
+		return nil
+	}
+	return fn(ctx, operation)
+}
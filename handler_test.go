@@ -0,0 +1,304 @@
+package redsys
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signNotification builds a notification of the bank signed with the test secret. The date of the operation is
+// filled in if missing, like the bank always does.
+func signNotification(t *testing.T, params map[string]string) Signed {
+	if _, ok := params["Ds_Date"]; !ok {
+		params["Ds_Date"] = "01/03/2024"
+		params["Ds_Hour"] = "10:00"
+	}
+	paramsJSON, err := json.Marshal(params)
+	require.NoError(t, err)
+	encoded := base64.StdEncoding.EncodeToString(paramsJSON)
+	signature, err := sign(testSecret, params["Ds_Order"], encoded)
+	require.NoError(t, err)
+	return Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           encoded,
+		Signature:        base64.URLEncoding.EncodeToString(signature),
+	}
+}
+
+func notificationForm(signed Signed) url.Values {
+	return url.Values{
+		"Ds_SignatureVersion":   {signed.SignatureVersion},
+		"Ds_MerchantParameters": {signed.Params},
+		"Ds_Signature":          {signed.Signature},
+	}
+}
+
+func TestHandlerPost(t *testing.T) {
+	var approved []Operation
+	handler := &Handler{
+		Secret: testSecret,
+		OnApproved: func(ctx context.Context, operation Operation) error {
+			approved = append(approved, operation)
+			return nil
+		},
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_Date": "01/03/2024", "Ds_Hour": "10:15"})
+
+	r := httptest.NewRequest(http.MethodPost, "/notification", strings.NewReader(notificationForm(signed).Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, w.Code, http.StatusOK)
+	require.Len(t, approved, 1)
+	require.Equal(t, approved[0].Params.Order, "00011234abcd")
+}
+
+func TestHandlerGet(t *testing.T) {
+	var cancelled int
+	handler := &Handler{
+		Secret: testSecret,
+		OnCancelled: func(ctx context.Context, operation Operation) error {
+			cancelled++
+			return nil
+		},
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "9915"})
+
+	r := httptest.NewRequest(http.MethodGet, "/notification?"+notificationForm(signed).Encode(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, w.Code, http.StatusOK)
+	require.Equal(t, cancelled, 1)
+}
+
+func TestHandlerMissingCallback(t *testing.T) {
+	handler := &Handler{Secret: testSecret}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0913"})
+
+	r := httptest.NewRequest(http.MethodGet, "/notification?"+notificationForm(signed).Encode(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestHandlerBadSignature(t *testing.T) {
+	var logs bytes.Buffer
+	handler := &Handler{
+		Secret: testSecret,
+		OnApproved: func(ctx context.Context, operation Operation) error {
+			t.Fatal("should not be called")
+			return nil
+		},
+		ErrorLog: log.New(&logs, "", 0),
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+	valid := signed.Signature
+	signed.Signature = "foobarqu"
+
+	r := httptest.NewRequest(http.MethodGet, "/notification?"+notificationForm(signed).Encode(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, w.Code, http.StatusBadRequest)
+	require.Equal(t, w.Body.String(), "invalid notification\n")
+	require.Contains(t, logs.String(), "bad signature")
+	require.NotContains(t, logs.String(), valid)
+}
+
+func TestHandlerIncomplete(t *testing.T) {
+	handler := &Handler{Secret: testSecret}
+
+	r := httptest.NewRequest(http.MethodGet, "/notification", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestHandlerMethodNotAllowed(t *testing.T) {
+	handler := &Handler{Secret: testSecret}
+
+	r := httptest.NewRequest(http.MethodPut, "/notification", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, w.Code, http.StatusMethodNotAllowed)
+}
+
+func TestHandlerCallbackError(t *testing.T) {
+	handler := &Handler{
+		Secret: testSecret,
+		OnApproved: func(ctx context.Context, operation Operation) error {
+			return fmt.Errorf("database unavailable")
+		},
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+
+	r := httptest.NewRequest(http.MethodGet, "/notification?"+notificationForm(signed).Encode(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, w.Code, http.StatusInternalServerError)
+}
+
+func TestHandlerDispatch(t *testing.T) {
+	var called []Status
+	record := func(ctx context.Context, operation Operation) error {
+		called = append(called, operation.Status)
+		return nil
+	}
+	handler := &Handler{
+		OnApproved:  record,
+		OnCancelled: record,
+		OnRepeated:  record,
+		OnUnknown:   record,
+	}
+
+	for _, status := range []Status{StatusApproved, StatusCancelled, StatusRepeated, StatusUnknown} {
+		require.NoError(t, handler.Dispatch(context.Background(), Operation{Status: status}))
+	}
+	require.Equal(t, called, []Status{StatusApproved, StatusCancelled, StatusRepeated, StatusUnknown})
+}
+
+func TestHandlerDeduplicates(t *testing.T) {
+	var approved int
+	handler := &Handler{
+		Secret: testSecret,
+		Store:  new(MemoryStore),
+		OnApproved: func(ctx context.Context, operation Operation) error {
+			approved++
+			return nil
+		},
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/notification?"+notificationForm(signed).Encode(), nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		require.Equal(t, w.Code, http.StatusOK)
+	}
+
+	// The user comes back to the URLOK page with the same parameters in the other base64 alphabet.
+	signed.Signature = strings.NewReplacer("-", "+", "_", "/").Replace(signed.Signature)
+	operation, err := handler.Process(context.Background(), signed)
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusApproved)
+
+	require.Equal(t, approved, 1)
+}
+
+func TestHandlerDeduplicatesReconciled(t *testing.T) {
+	var approved int
+	handler := &Handler{
+		Secret: testSecret,
+		Store:  new(MemoryStore),
+		OnApproved: func(ctx context.Context, operation Operation) error {
+			approved++
+			return nil
+		},
+	}
+
+	// The reconciler emits the order before the late notification arrives.
+	reconciled := Operation{
+		Status:     StatusApproved,
+		Params:     Params{Order: "00011234abcd", Response: 0, RawResponse: "0"},
+		Reconciled: true,
+	}
+	require.NoError(t, handler.Dispatch(context.Background(), reconciled))
+
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+	r := httptest.NewRequest(http.MethodGet, "/notification?"+notificationForm(signed).Encode(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, w.Code, http.StatusOK)
+
+	require.Equal(t, approved, 1)
+}
+
+func TestHandlerDeduplicatesDistinctOutcomes(t *testing.T) {
+	var called []Status
+	record := func(ctx context.Context, operation Operation) error {
+		called = append(called, operation.Status)
+		return nil
+	}
+	handler := &Handler{
+		Secret:      testSecret,
+		Store:       new(MemoryStore),
+		OnApproved:  record,
+		OnCancelled: record,
+	}
+
+	_, err := handler.Process(context.Background(), signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "9915"}))
+	require.NoError(t, err)
+	_, err = handler.Process(context.Background(), signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"}))
+	require.NoError(t, err)
+
+	require.Equal(t, called, []Status{StatusCancelled, StatusApproved})
+}
+
+func TestHandlerDeduplicatesRetriesFailures(t *testing.T) {
+	var calls int
+	handler := &Handler{
+		Secret: testSecret,
+		Store:  new(MemoryStore),
+		OnApproved: func(ctx context.Context, operation Operation) error {
+			calls++
+			if calls == 1 {
+				return fmt.Errorf("database unavailable")
+			}
+			return nil
+		},
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+
+	_, err := handler.Process(context.Background(), signed)
+	require.EqualError(t, err, "database unavailable")
+	_, err = handler.Process(context.Background(), signed)
+	require.NoError(t, err)
+	_, err = handler.Process(context.Background(), signed)
+	require.NoError(t, err)
+
+	require.Equal(t, calls, 2)
+}
+
+func TestHandlerDispatchOutcome(t *testing.T) {
+	var called []string
+	record := func(name string) OperationFunc {
+		return func(ctx context.Context, operation Operation) error {
+			called = append(called, name)
+			return nil
+		}
+	}
+	handler := &Handler{
+		OnCancelled: record("cancelled"),
+		OnUnknown:   record("unknown"),
+		OnDenied:    record("denied"),
+		OnPending:   record("pending"),
+	}
+
+	operations := []Operation{
+		{Status: StatusCancelled, Outcome: StatusDenied},
+		{Status: StatusCancelled, Outcome: StatusFraud},
+		{Status: StatusCancelled, Outcome: StatusCancelled},
+		{Status: StatusUnknown, Outcome: StatusPending},
+		{Status: StatusUnknown, Outcome: StatusError},
+	}
+	for _, operation := range operations {
+		require.NoError(t, handler.Dispatch(context.Background(), operation))
+	}
+	require.Equal(t, called, []string{"denied", "cancelled", "cancelled", "pending", "unknown"})
+}
@@ -0,0 +1,61 @@
+package redsys
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+const (
+	InSiteScriptProduction = "https://sis.redsys.es/sis/NC/redsysV3.js"
+	InSiteScriptDebug      = "https://sis-t.redsys.es:25443/sis/NC/sandbox/redsysV3.js"
+)
+
+// InSiteConfig is the configuration the InSite Javascript library needs to render the card form in the checkout
+// page. It can be sent directly as JSON to the browser.
+type InSiteConfig struct {
+	// URL of the script to load in the page.
+	Script string `json:"script"`
+
+	// Merchant code, named FUC in the InSite documentation.
+	MerchantCode string `json:"merchantCode"`
+
+	// Terminal number as a string.
+	Terminal string `json:"terminal"`
+
+	// Order code of the payment. The same code should be used later to authorize the operation.
+	Order string `json:"order"`
+}
+
+// NewInSiteConfig prepares the configuration of the InSite card form for a new payment.
+func NewInSiteConfig(merchant Merchant, order string) (InSiteConfig, error) {
+	if !reOrder.MatchString(order) {
+		return InSiteConfig{}, fmt.Errorf("invalid order format %q", order)
+	}
+	config := InSiteConfig{
+		Script:       InSiteScriptProduction,
+		MerchantCode: merchant.Code,
+		Terminal:     strconv.FormatInt(merchant.Terminal, 10),
+		Order:        order,
+	}
+	if merchant.Debug {
+		config.Script = InSiteScriptDebug
+	}
+	return config, nil
+}
+
+// AuthorizeInSite charges the card introduced by the customer in the InSite form using the operation ID the form
+// returned in the browser. The order should be the same one used in the InSite configuration.
+//
+// If the card requires an EMV3DS authentication use a ThreeDSFlow with the IDOper field of the request instead.
+func (client *Client) AuthorizeInSite(ctx context.Context, merchant Merchant, order string, amount int32, idOper string) (Operation, error) {
+	if idOper == "" {
+		return Operation{}, fmt.Errorf("missing InSite operation ID of order %q", order)
+	}
+	return client.Send(ctx, merchant, Request{
+		TransactionType: TransactionTypeSimpleAuthorization,
+		Order:           order,
+		Amount:          amount,
+		IDOper:          idOper,
+	})
+}
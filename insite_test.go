@@ -0,0 +1,52 @@
+package redsys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInSiteConfig(t *testing.T) {
+	merchant := testMerchant()
+	merchant.Debug = true
+	config, err := NewInSiteConfig(merchant, "00011234abcd")
+	require.NoError(t, err)
+
+	require.Equal(t, config, InSiteConfig{
+		Script:       InSiteScriptDebug,
+		MerchantCode: "123456789",
+		Terminal:     "1",
+		Order:        "00011234abcd",
+	})
+}
+
+func TestNewInSiteConfigInvalidOrder(t *testing.T) {
+	_, err := NewInSiteConfig(testMerchant(), "0001")
+	require.EqualError(t, err, `invalid order format "0001"`)
+}
+
+func TestAuthorizeInSite(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		require.Equal(t, params["Ds_Merchant_IdOper"], "c3b1e25f0b64c9ac7d1c0a45f43c1e8e3f0f1a5b")
+		require.Equal(t, params["Ds_Merchant_Amount"], float64(1000))
+		return map[string]interface{}{
+			"Ds_Order":     "00011234abcd",
+			"Ds_Response":  "0000",
+			"Ds_Card_Type": "C",
+		}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operation, err := client.AuthorizeInSite(context.Background(), testMerchant(), "00011234abcd", 1000, "c3b1e25f0b64c9ac7d1c0a45f43c1e8e3f0f1a5b")
+	require.NoError(t, err)
+
+	require.Equal(t, operation.Status, StatusApproved)
+	require.True(t, operation.IsCreditCard)
+}
+
+func TestAuthorizeInSiteMissingOperation(t *testing.T) {
+	client := new(Client)
+	_, err := client.AuthorizeInSite(context.Background(), testMerchant(), "00011234abcd", 1000, "")
+	require.EqualError(t, err, `missing InSite operation ID of order "00011234abcd"`)
+}
@@ -0,0 +1,83 @@
+package redsys
+
+import (
+	"context"
+	"crypto/hmac"
+	"fmt"
+)
+
+// Key to sign and verify transactions.
+type Key struct {
+	// Version of the key to identify it, e.g. the date of the rotation. It is reported back in the operations
+	// verified with the key.
+	Version string
+
+	// Secret assigned by the bank.
+	Secret string
+
+	// Signer that keeps the secret outside of the application. If configured Secret is ignored.
+	Signer Signer
+}
+
+// KeySet of the active keys of a merchant. The first key is the primary one used to sign the transactions; the rest
+// are only accepted to verify the notifications of transactions signed before a rotation.
+type KeySet []Key
+
+// signer returns the signer of the key.
+func (key Key) signer() Signer {
+	if key.Signer != nil {
+		return key.Signer
+	}
+	return LocalSigner{Secret: key.Secret}
+}
+
+// keys returns the keys of the merchant. A merchant without a key set has a single unversioned key with the signer
+// or the secret.
+func (merchant Merchant) keys() KeySet {
+	if len(merchant.Keys) > 0 {
+		return merchant.Keys
+	}
+	return KeySet{{Secret: merchant.Secret, Signer: merchant.Signer}}
+}
+
+// signer returns the signer of the primary key of the merchant.
+func (merchant Merchant) signer() Signer {
+	return merchant.keys()[0].signer()
+}
+
+// ConfirmKeys works like Confirm but accepts the notifications signed with any of the keys of the set. The version
+// of the key that matched is reported in the operation.
+func ConfirmKeys(ctx context.Context, keys KeySet, signed Signed) (Operation, error) {
+	params, key, err := verify(ctx, keys, signed)
+	if err != nil {
+		return Operation{}, err
+	}
+	operation, err := newOperation(params)
+	if err != nil {
+		return Operation{}, err
+	}
+	operation.KeyVersion = key.Version
+	return operation, nil
+}
+
+// matchKey signs the content with all the keys and returns the one that matches the signature. Every key is always
+// checked to not leak through the timing of the verification which one of them matched.
+func matchKey(ctx context.Context, keys KeySet, order, content string, signature []byte) (Key, bool, error) {
+	if len(keys) == 0 {
+		return Key{}, false, fmt.Errorf("no keys to verify the signature")
+	}
+
+	var matched Key
+	var found bool
+	for _, key := range keys {
+		computed, err := key.signer().Sign(ctx, order, content)
+		if err != nil {
+			return Key{}, false, err
+		}
+		if hmac.Equal(computed, signature) && !found {
+			matched = key
+			found = true
+		}
+	}
+	return matched, found, nil
+}
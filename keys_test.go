@@ -0,0 +1,65 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testOldSecret = "QUJDREVGR0hJSktMTU5PUFFSU1RVVldY"
+
+func TestConfirmKeys(t *testing.T) {
+	keys := KeySet{
+		{Version: "2024-03", Secret: testSecret},
+		{Version: "2023-09", Secret: testOldSecret},
+	}
+
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+	operation, err := ConfirmKeys(context.Background(), keys, signed)
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusApproved)
+	require.Equal(t, operation.KeyVersion, "2024-03")
+
+	signed = Signed{SignatureVersion: "HMAC_SHA256_V1", Params: signed.Params}
+	signature, err := sign(testOldSecret, "00011234abcd", signed.Params)
+	require.NoError(t, err)
+	signed.Signature = base64.URLEncoding.EncodeToString(signature)
+	operation, err = ConfirmKeys(context.Background(), keys, signed)
+	require.NoError(t, err)
+	require.Equal(t, operation.KeyVersion, "2023-09")
+}
+
+func TestConfirmKeysUnknownKey(t *testing.T) {
+	keys := KeySet{
+		{Version: "2023-09", Secret: testOldSecret},
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+
+	_, err := ConfirmKeys(context.Background(), keys, signed)
+	require.ErrorContains(t, err, "bad signature")
+}
+
+func TestConfirmKeysEmpty(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+
+	_, err := ConfirmKeys(context.Background(), nil, signed)
+	require.EqualError(t, err, "no keys to verify the signature")
+}
+
+func TestSignPrimaryKey(t *testing.T) {
+	merchant := Merchant{
+		Secret: "ignored",
+		Keys: KeySet{
+			{Version: "2024-03", Secret: testSecret},
+			{Version: "2023-09", Secret: testOldSecret},
+		},
+	}
+	signed, err := Sign(context.Background(), merchant, Session{Order: "00011234abcd"})
+	require.NoError(t, err)
+
+	signature, err := sign(testSecret, "00011234abcd", signed.Params)
+	require.NoError(t, err)
+	require.Equal(t, signed.Signature, base64.URLEncoding.EncodeToString(signature))
+}
@@ -0,0 +1,164 @@
+package redsys
+
+import (
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+// Lang is the code of the language of the payment page of the bank.
+type Lang string
+
+const (
+	LangES = Lang("001")
+	LangEN = Lang("002")
+	LangCA = Lang("003")
+	LangFR = Lang("004")
+	LangDE = Lang("005")
+	LangNL = Lang("006")
+	LangIT = Lang("007")
+	LangSV = Lang("008")
+	LangPT = Lang("009")
+	LangVA = Lang("010")
+	LangPL = Lang("011")
+	LangGL = Lang("012")
+	LangEU = Lang("013")
+	LangBG = Lang("100")
+	LangZH = Lang("156")
+	LangHR = Lang("191")
+	LangCS = Lang("203")
+	LangDA = Lang("208")
+	LangET = Lang("233")
+	LangFI = Lang("246")
+	LangEL = Lang("300")
+	LangHU = Lang("348")
+	LangJA = Lang("392")
+	LangLV = Lang("428")
+	LangLT = Lang("440")
+	LangMT = Lang("470")
+	LangRO = Lang("642")
+	LangRU = Lang("643")
+	LangSK = Lang("703")
+	LangSL = Lang("705")
+	LangTR = Lang("792")
+)
+
+// Languages supported by Redsys. English goes first because it is the fallback of the matcher.
+var langs = []struct {
+	lang Lang
+	tag  language.Tag
+}{
+	{LangEN, language.English},
+	{LangES, language.Spanish},
+	{LangCA, language.Catalan},
+	{LangFR, language.French},
+	{LangDE, language.German},
+	{LangNL, language.Dutch},
+	{LangIT, language.Italian},
+	{LangSV, language.Swedish},
+	{LangPT, language.Portuguese},
+	{LangVA, language.MustParse("ca-ES-valencia")},
+	{LangPL, language.Polish},
+	{LangGL, language.MustParse("gl")},
+	{LangEU, language.MustParse("eu")},
+	{LangBG, language.Bulgarian},
+	{LangZH, language.Chinese},
+	{LangHR, language.Croatian},
+	{LangCS, language.Czech},
+	{LangDA, language.Danish},
+	{LangET, language.Estonian},
+	{LangFI, language.Finnish},
+	{LangEL, language.Greek},
+	{LangHU, language.Hungarian},
+	{LangJA, language.Japanese},
+	{LangLV, language.Latvian},
+	{LangLT, language.Lithuanian},
+	{LangMT, language.MustParse("mt")},
+	{LangRO, language.Romanian},
+	{LangRU, language.Russian},
+	{LangSK, language.Slovak},
+	{LangSL, language.Slovenian},
+	{LangTR, language.Turkish},
+}
+
+var (
+	valencia                  = mustParseVariant("valencia")
+	langMatcher, langsMatched = newLangMatcher()
+)
+
+// newLangMatcher builds the matcher of the languages. The matcher ignores the variants, so Valencian is left out
+// and detected afterwards from the Catalan matches.
+func newLangMatcher() (language.Matcher, []Lang) {
+	var tags []language.Tag
+	var matched []Lang
+	for _, l := range langs {
+		if l.lang == LangVA {
+			continue
+		}
+		tags = append(tags, l.tag)
+		matched = append(matched, l.lang)
+	}
+	return language.NewMatcher(tags), matched
+}
+
+// Valid returns true if the language is supported by Redsys.
+func (lang Lang) Valid() bool {
+	for _, l := range langs {
+		if l.lang == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Tag returns the BCP 47 tag of the language. It will be undefined for unknown languages.
+func (lang Lang) Tag() language.Tag {
+	for _, l := range langs {
+		if l.lang == lang {
+			return l.tag
+		}
+	}
+	return language.Und
+}
+
+// LangFromTags returns the supported language that best matches the tags, ordered by preference. It returns
+// English if none of them is supported.
+func LangFromTags(tags ...language.Tag) Lang {
+	_, index, confidence := langMatcher.Match(tags...)
+	if confidence == language.No {
+		return LangEN
+	}
+	lang := langsMatched[index]
+
+	if lang == LangCA {
+		for _, tag := range tags {
+			if base, _ := tag.Base(); base.String() != "ca" {
+				continue
+			}
+			if slices.Contains(tag.Variants(), valencia) {
+				return LangVA
+			}
+			break
+		}
+	}
+
+	return lang
+}
+
+// LangFromAcceptLanguage returns the supported language that best matches an Accept-Language header. It returns
+// English if the header is invalid or none of its languages is supported.
+func LangFromAcceptLanguage(header string) Lang {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return LangEN
+	}
+	return LangFromTags(tags...)
+}
+
+func mustParseVariant(s string) language.Variant {
+	variant, err := language.ParseVariant(s)
+	if err != nil {
+		panic(err)
+	}
+	return variant
+}
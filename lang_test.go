@@ -0,0 +1,57 @@
+package redsys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestLangValid(t *testing.T) {
+	require.True(t, LangES.Valid())
+	require.True(t, LangTR.Valid())
+	require.False(t, Lang("999").Valid())
+	require.False(t, Lang("").Valid())
+}
+
+func TestLangFromTags(t *testing.T) {
+	tests := []struct {
+		tag  string
+		lang Lang
+	}{
+		{"es", LangES},
+		{"es-MX", LangES},
+		{"en-US", LangEN},
+		{"ca", LangCA},
+		{"ca-ES-valencia", LangVA},
+		{"pt-BR", LangPT},
+		{"sv-SE", LangSV},
+		{"nl-BE", LangNL},
+		{"ja", LangJA},
+		{"zh-Hans-CN", LangZH},
+		{"eu", LangEU},
+		{"gl", LangGL},
+		{"sw", LangEN},
+	}
+	for _, test := range tests {
+		require.Equal(t, LangFromTags(language.MustParse(test.tag)), test.lang, test.tag)
+	}
+}
+
+func TestLangFromAcceptLanguage(t *testing.T) {
+	require.Equal(t, LangFromAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5"), LangFR)
+	require.Equal(t, LangFromAcceptLanguage("sw, pl;q=0.8"), LangPL)
+	require.Equal(t, LangFromAcceptLanguage("sw"), LangEN)
+	require.Equal(t, LangFromAcceptLanguage(""), LangEN)
+	require.Equal(t, LangFromAcceptLanguage("!!!"), LangEN)
+}
+
+func TestSignInvalidLang(t *testing.T) {
+	merchant := Merchant{Secret: testSecret}
+	_, err := Sign(context.Background(), merchant, Session{Order: "00011234abcd", Lang: Lang("999")})
+	require.EqualError(t, err, `unsupported language "999"`)
+
+	_, err = Sign(context.Background(), merchant, Session{Order: "00011234abcd", Lang: LangJA})
+	require.NoError(t, err)
+}
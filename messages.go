@@ -0,0 +1,450 @@
+package redsys
+
+import (
+	"slices"
+)
+
+type messageKey int
+
+const (
+	messageGeneric = messageKey(iota)
+	messageApproved
+	messageDenied
+	messageExpiredCard
+	messageInsufficientFunds
+	messageWrongCardData
+	messageAuthentication
+	messageCancelled
+	messageTechnical
+	messagePending
+)
+
+// Denied response codes with a more specific message than the one of their category.
+var (
+	expiredCardCodes       = []int64{101, 201, 9071}
+	insufficientFundsCodes = []int64{116}
+	wrongCardDataCodes     = []int64{129, 191, 280, 9064, 9065, 9066, 9067, 9068, 9069, 9070, 9216, 9217, 9221, 9253}
+)
+
+// Messages for the customers. They should never explain the reason of a fraud denial, those operations show the
+// generic denial message instead.
+var customerMessages = map[Lang]map[messageKey]string{
+	LangES: {
+		messageGeneric:           "No se ha podido completar el pago. Por favor, inténtalo de nuevo.",
+		messageApproved:          "El pago se ha completado correctamente.",
+		messageDenied:            "El pago ha sido denegado. Por favor, utiliza otra tarjeta o contacta con tu banco.",
+		messageExpiredCard:       "Tu tarjeta ha caducado. Por favor, utiliza otra tarjeta.",
+		messageInsufficientFunds: "La tarjeta no tiene saldo suficiente. Por favor, utiliza otra tarjeta.",
+		messageWrongCardData:     "Los datos de la tarjeta no son correctos. Por favor, revísalos e inténtalo de nuevo.",
+		messageAuthentication:    "No se ha podido verificar tu identidad. Por favor, contacta con tu banco.",
+		messageCancelled:         "Has cancelado el pago.",
+		messageTechnical:         "Se ha producido un error técnico. Por favor, inténtalo de nuevo más tarde.",
+		messagePending:           "El pago está pendiente de confirmación.",
+	},
+	LangEN: {
+		messageGeneric:           "The payment could not be completed. Please try again.",
+		messageApproved:          "The payment has been completed successfully.",
+		messageDenied:            "The payment has been declined. Please use another card or contact your bank.",
+		messageExpiredCard:       "Your card has expired. Please use another card.",
+		messageInsufficientFunds: "The card does not have enough funds. Please use another card.",
+		messageWrongCardData:     "The card details are not correct. Please check them and try again.",
+		messageAuthentication:    "Authentication failed. Please contact your bank.",
+		messageCancelled:         "You have cancelled the payment.",
+		messageTechnical:         "A technical error has occurred. Please try again later.",
+		messagePending:           "The payment is pending confirmation.",
+	},
+	LangCA: {
+		messageGeneric:           "No s'ha pogut completar el pagament. Si us plau, torna-ho a provar.",
+		messageApproved:          "El pagament s'ha completat correctament.",
+		messageDenied:            "El pagament ha estat denegat. Si us plau, utilitza una altra targeta o contacta amb el teu banc.",
+		messageExpiredCard:       "La teva targeta ha caducat. Si us plau, utilitza una altra targeta.",
+		messageInsufficientFunds: "La targeta no té saldo suficient. Si us plau, utilitza una altra targeta.",
+		messageWrongCardData:     "Les dades de la targeta no són correctes. Si us plau, revisa-les i torna-ho a provar.",
+		messageAuthentication:    "No s'ha pogut verificar la teva identitat. Si us plau, contacta amb el teu banc.",
+		messageCancelled:         "Has cancel·lat el pagament.",
+		messageTechnical:         "S'ha produït un error tècnic. Si us plau, torna-ho a provar més tard.",
+		messagePending:           "El pagament està pendent de confirmació.",
+	},
+	LangFR: {
+		messageGeneric:           "Le paiement n'a pas pu être effectué. Veuillez réessayer.",
+		messageApproved:          "Le paiement a été effectué avec succès.",
+		messageDenied:            "Le paiement a été refusé. Veuillez utiliser une autre carte ou contacter votre banque.",
+		messageExpiredCard:       "Votre carte a expiré. Veuillez utiliser une autre carte.",
+		messageInsufficientFunds: "Le solde de la carte est insuffisant. Veuillez utiliser une autre carte.",
+		messageWrongCardData:     "Les données de la carte sont incorrectes. Veuillez les vérifier et réessayer.",
+		messageAuthentication:    "L'authentification a échoué. Veuillez contacter votre banque.",
+		messageCancelled:         "Vous avez annulé le paiement.",
+		messageTechnical:         "Une erreur technique s'est produite. Veuillez réessayer plus tard.",
+		messagePending:           "Le paiement est en attente de confirmation.",
+	},
+	LangDE: {
+		messageGeneric:           "Die Zahlung konnte nicht abgeschlossen werden. Bitte versuchen Sie es erneut.",
+		messageApproved:          "Die Zahlung wurde erfolgreich abgeschlossen.",
+		messageDenied:            "Die Zahlung wurde abgelehnt. Bitte verwenden Sie eine andere Karte oder wenden Sie sich an Ihre Bank.",
+		messageExpiredCard:       "Ihre Karte ist abgelaufen. Bitte verwenden Sie eine andere Karte.",
+		messageInsufficientFunds: "Die Karte ist nicht ausreichend gedeckt. Bitte verwenden Sie eine andere Karte.",
+		messageWrongCardData:     "Die Kartendaten sind nicht korrekt. Bitte überprüfen Sie sie und versuchen Sie es erneut.",
+		messageAuthentication:    "Die Authentifizierung ist fehlgeschlagen. Bitte wenden Sie sich an Ihre Bank.",
+		messageCancelled:         "Sie haben die Zahlung abgebrochen.",
+		messageTechnical:         "Ein technischer Fehler ist aufgetreten. Bitte versuchen Sie es später erneut.",
+		messagePending:           "Die Zahlung wartet auf Bestätigung.",
+	},
+	LangIT: {
+		messageGeneric:           "Non è stato possibile completare il pagamento. Si prega di riprovare.",
+		messageApproved:          "Il pagamento è stato completato correttamente.",
+		messageDenied:            "Il pagamento è stato rifiutato. Si prega di utilizzare un'altra carta o di contattare la propria banca.",
+		messageExpiredCard:       "La carta è scaduta. Si prega di utilizzare un'altra carta.",
+		messageInsufficientFunds: "La carta non ha fondi sufficienti. Si prega di utilizzare un'altra carta.",
+		messageWrongCardData:     "I dati della carta non sono corretti. Si prega di verificarli e riprovare.",
+		messageAuthentication:    "L'autenticazione non è riuscita. Si prega di contattare la propria banca.",
+		messageCancelled:         "Hai annullato il pagamento.",
+		messageTechnical:         "Si è verificato un errore tecnico. Si prega di riprovare più tardi.",
+		messagePending:           "Il pagamento è in attesa di conferma.",
+	},
+	LangPT: {
+		messageGeneric:           "Não foi possível concluir o pagamento. Por favor, tente novamente.",
+		messageApproved:          "O pagamento foi concluído com sucesso.",
+		messageDenied:            "O pagamento foi recusado. Por favor, utilize outro cartão ou contacte o seu banco.",
+		messageExpiredCard:       "O seu cartão expirou. Por favor, utilize outro cartão.",
+		messageInsufficientFunds: "O cartão não tem saldo suficiente. Por favor, utilize outro cartão.",
+		messageWrongCardData:     "Os dados do cartão não estão corretos. Por favor, verifique-os e tente novamente.",
+		messageAuthentication:    "A autenticação falhou. Por favor, contacte o seu banco.",
+		messageCancelled:         "Cancelou o pagamento.",
+		messageTechnical:         "Ocorreu um erro técnico. Por favor, tente novamente mais tarde.",
+		messagePending:           "O pagamento está pendente de confirmação.",
+	},
+	LangNL: {
+		messageGeneric:           "De betaling kon niet worden voltooid. Probeer het opnieuw.",
+		messageApproved:          "De betaling is succesvol voltooid.",
+		messageDenied:            "De betaling is geweigerd. Gebruik een andere kaart of neem contact op met uw bank.",
+		messageExpiredCard:       "Uw kaart is verlopen. Gebruik een andere kaart.",
+		messageInsufficientFunds: "Het saldo van de kaart is onvoldoende. Gebruik een andere kaart.",
+		messageWrongCardData:     "De kaartgegevens zijn onjuist. Controleer ze en probeer het opnieuw.",
+		messageAuthentication:    "De verificatie is mislukt. Neem contact op met uw bank.",
+		messageCancelled:         "U hebt de betaling geannuleerd.",
+		messageTechnical:         "Er is een technische fout opgetreden. Probeer het later opnieuw.",
+		messagePending:           "De betaling wacht op bevestiging.",
+	},
+	LangSV: {
+		messageGeneric:           "Betalningen kunde inte genomföras. Försök igen.",
+		messageApproved:          "Betalningen har genomförts.",
+		messageDenied:            "Betalningen har nekats. Använd ett annat kort eller kontakta din bank.",
+		messageExpiredCard:       "Ditt kort har gått ut. Använd ett annat kort.",
+		messageInsufficientFunds: "Det finns inte tillräckligt med pengar på kortet. Använd ett annat kort.",
+		messageWrongCardData:     "Kortuppgifterna är felaktiga. Kontrollera dem och försök igen.",
+		messageAuthentication:    "Autentiseringen misslyckades. Kontakta din bank.",
+		messageCancelled:         "Du har avbrutit betalningen.",
+		messageTechnical:         "Ett tekniskt fel har uppstått. Försök igen senare.",
+		messagePending:           "Betalningen väntar på bekräftelse.",
+	},
+	LangVA: {
+		messageGeneric:           "No s'ha pogut completar el pagament. Per favor, torna-ho a provar.",
+		messageApproved:          "El pagament s'ha completat correctament.",
+		messageDenied:            "El pagament ha sigut denegat. Per favor, utilitza una altra targeta o contacta amb el teu banc.",
+		messageExpiredCard:       "La teua targeta ha caducat. Per favor, utilitza una altra targeta.",
+		messageInsufficientFunds: "La targeta no té saldo suficient. Per favor, utilitza una altra targeta.",
+		messageWrongCardData:     "Les dades de la targeta no són correctes. Per favor, revisa-les i torna-ho a provar.",
+		messageAuthentication:    "No s'ha pogut verificar la teua identitat. Per favor, contacta amb el teu banc.",
+		messageCancelled:         "Has cancel·lat el pagament.",
+		messageTechnical:         "S'ha produït un error tècnic. Per favor, torna-ho a provar més tard.",
+		messagePending:           "El pagament està pendent de confirmació.",
+	},
+	LangPL: {
+		messageGeneric:           "Nie udało się zrealizować płatności. Spróbuj ponownie.",
+		messageApproved:          "Płatność została zrealizowana pomyślnie.",
+		messageDenied:            "Płatność została odrzucona. Użyj innej karty lub skontaktuj się ze swoim bankiem.",
+		messageExpiredCard:       "Twoja karta straciła ważność. Użyj innej karty.",
+		messageInsufficientFunds: "Na karcie brakuje wystarczających środków. Użyj innej karty.",
+		messageWrongCardData:     "Dane karty są nieprawidłowe. Sprawdź je i spróbuj ponownie.",
+		messageAuthentication:    "Uwierzytelnianie nie powiodło się. Skontaktuj się ze swoim bankiem.",
+		messageCancelled:         "Płatność została anulowana.",
+		messageTechnical:         "Wystąpił błąd techniczny. Spróbuj ponownie później.",
+		messagePending:           "Płatność oczekuje na potwierdzenie.",
+	},
+	LangGL: {
+		messageGeneric:           "Non foi posible completar o pagamento. Por favor, téntao de novo.",
+		messageApproved:          "O pagamento completouse correctamente.",
+		messageDenied:            "O pagamento foi denegado. Por favor, utiliza outra tarxeta ou contacta co teu banco.",
+		messageExpiredCard:       "A túa tarxeta caducou. Por favor, utiliza outra tarxeta.",
+		messageInsufficientFunds: "A tarxeta non ten saldo suficiente. Por favor, utiliza outra tarxeta.",
+		messageWrongCardData:     "Os datos da tarxeta non son correctos. Por favor, revísaos e téntao de novo.",
+		messageAuthentication:    "Non foi posible verificar a túa identidade. Por favor, contacta co teu banco.",
+		messageCancelled:         "Cancelaches o pagamento.",
+		messageTechnical:         "Produciuse un erro técnico. Por favor, téntao de novo máis tarde.",
+		messagePending:           "O pagamento está pendente de confirmación.",
+	},
+	LangEU: {
+		messageGeneric:           "Ezin izan da ordainketa osatu. Saiatu berriro, mesedez.",
+		messageApproved:          "Ordainketa behar bezala osatu da.",
+		messageDenied:            "Ordainketa ukatu egin da. Erabili beste txartel bat edo jarri harremanetan zure bankuarekin.",
+		messageExpiredCard:       "Zure txartela iraungita dago. Erabili beste txartel bat.",
+		messageInsufficientFunds: "Txartelak ez du saldo nahikorik. Erabili beste txartel bat.",
+		messageWrongCardData:     "Txartelaren datuak ez dira zuzenak. Berrikusi eta saiatu berriro.",
+		messageAuthentication:    "Ezin izan da zure nortasuna egiaztatu. Jarri harremanetan zure bankuarekin.",
+		messageCancelled:         "Ordainketa bertan behera utzi duzu.",
+		messageTechnical:         "Errore tekniko bat gertatu da. Saiatu berriro geroago.",
+		messagePending:           "Ordainketa berrespenaren zain dago.",
+	},
+	LangBG: {
+		messageGeneric:           "Плащането не можа да бъде извършено. Моля, опитайте отново.",
+		messageApproved:          "Плащането е извършено успешно.",
+		messageDenied:            "Плащането е отказано. Моля, използвайте друга карта или се свържете с банката си.",
+		messageExpiredCard:       "Картата ви е с изтекъл срок на валидност. Моля, използвайте друга карта.",
+		messageInsufficientFunds: "Наличността по картата е недостатъчна. Моля, използвайте друга карта.",
+		messageWrongCardData:     "Данните на картата са неправилни. Моля, проверете ги и опитайте отново.",
+		messageAuthentication:    "Удостоверяването е неуспешно. Моля, свържете се с банката си.",
+		messageCancelled:         "Отказахте плащането.",
+		messageTechnical:         "Възникна техническа грешка. Моля, опитайте отново по-късно.",
+		messagePending:           "Плащането очаква потвърждение.",
+	},
+	LangZH: {
+		messageGeneric:           "无法完成付款。请重试。",
+		messageApproved:          "付款已成功完成。",
+		messageDenied:            "付款被拒绝。请使用其他卡或联系您的银行。",
+		messageExpiredCard:       "您的卡已过期。请使用其他卡。",
+		messageInsufficientFunds: "卡内余额不足。请使用其他卡。",
+		messageWrongCardData:     "卡信息不正确。请检查后重试。",
+		messageAuthentication:    "身份验证失败。请联系您的银行。",
+		messageCancelled:         "您已取消付款。",
+		messageTechnical:         "发生技术错误。请稍后重试。",
+		messagePending:           "付款正在等待确认。",
+	},
+	LangHR: {
+		messageGeneric:           "Plaćanje nije moguće dovršiti. Molimo pokušajte ponovno.",
+		messageApproved:          "Plaćanje je uspješno dovršeno.",
+		messageDenied:            "Plaćanje je odbijeno. Molimo upotrijebite drugu karticu ili se obratite svojoj banci.",
+		messageExpiredCard:       "Vaša kartica je istekla. Molimo upotrijebite drugu karticu.",
+		messageInsufficientFunds: "Na kartici nema dovoljno sredstava. Molimo upotrijebite drugu karticu.",
+		messageWrongCardData:     "Podaci o kartici nisu ispravni. Molimo provjerite ih i pokušajte ponovno.",
+		messageAuthentication:    "Autentifikacija nije uspjela. Molimo obratite se svojoj banci.",
+		messageCancelled:         "Otkazali ste plaćanje.",
+		messageTechnical:         "Došlo je do tehničke pogreške. Molimo pokušajte ponovno kasnije.",
+		messagePending:           "Plaćanje čeka potvrdu.",
+	},
+	LangCS: {
+		messageGeneric:           "Platbu se nepodařilo dokončit. Zkuste to prosím znovu.",
+		messageApproved:          "Platba byla úspěšně dokončena.",
+		messageDenied:            "Platba byla zamítnuta. Použijte prosím jinou kartu nebo kontaktujte svou banku.",
+		messageExpiredCard:       "Platnost vaší karty vypršela. Použijte prosím jinou kartu.",
+		messageInsufficientFunds: "Na kartě není dostatek prostředků. Použijte prosím jinou kartu.",
+		messageWrongCardData:     "Údaje o kartě nejsou správné. Zkontrolujte je prosím a zkuste to znovu.",
+		messageAuthentication:    "Ověření se nezdařilo. Kontaktujte prosím svou banku.",
+		messageCancelled:         "Zrušili jste platbu.",
+		messageTechnical:         "Došlo k technické chybě. Zkuste to prosím později.",
+		messagePending:           "Platba čeká na potvrzení.",
+	},
+	LangDA: {
+		messageGeneric:           "Betalingen kunne ikke gennemføres. Prøv venligst igen.",
+		messageApproved:          "Betalingen er gennemført.",
+		messageDenied:            "Betalingen er blevet afvist. Brug venligst et andet kort eller kontakt din bank.",
+		messageExpiredCard:       "Dit kort er udløbet. Brug venligst et andet kort.",
+		messageInsufficientFunds: "Der er ikke tilstrækkelig dækning på kortet. Brug venligst et andet kort.",
+		messageWrongCardData:     "Kortoplysningerne er ikke korrekte. Kontroller dem og prøv igen.",
+		messageAuthentication:    "Godkendelsen mislykkedes. Kontakt venligst din bank.",
+		messageCancelled:         "Du har annulleret betalingen.",
+		messageTechnical:         "Der opstod en teknisk fejl. Prøv venligst igen senere.",
+		messagePending:           "Betalingen afventer bekræftelse.",
+	},
+	LangET: {
+		messageGeneric:           "Makset ei õnnestunud lõpule viia. Palun proovige uuesti.",
+		messageApproved:          "Makse on edukalt sooritatud.",
+		messageDenied:            "Makse lükati tagasi. Palun kasutage teist kaarti või võtke ühendust oma pangaga.",
+		messageExpiredCard:       "Teie kaart on aegunud. Palun kasutage teist kaarti.",
+		messageInsufficientFunds: "Kaardil ei ole piisavalt vahendeid. Palun kasutage teist kaarti.",
+		messageWrongCardData:     "Kaardi andmed ei ole õiged. Palun kontrollige neid ja proovige uuesti.",
+		messageAuthentication:    "Autentimine ebaõnnestus. Palun võtke ühendust oma pangaga.",
+		messageCancelled:         "Te tühistasite makse.",
+		messageTechnical:         "Tekkis tehniline viga. Palun proovige hiljem uuesti.",
+		messagePending:           "Makse ootab kinnitust.",
+	},
+	LangFI: {
+		messageGeneric:           "Maksua ei voitu suorittaa. Yritä uudelleen.",
+		messageApproved:          "Maksu on suoritettu onnistuneesti.",
+		messageDenied:            "Maksu on hylätty. Käytä toista korttia tai ota yhteyttä pankkiisi.",
+		messageExpiredCard:       "Korttisi on vanhentunut. Käytä toista korttia.",
+		messageInsufficientFunds: "Kortilla ei ole riittävästi katetta. Käytä toista korttia.",
+		messageWrongCardData:     "Kortin tiedot ovat virheelliset. Tarkista ne ja yritä uudelleen.",
+		messageAuthentication:    "Tunnistautuminen epäonnistui. Ota yhteyttä pankkiisi.",
+		messageCancelled:         "Peruutit maksun.",
+		messageTechnical:         "Tapahtui tekninen virhe. Yritä myöhemmin uudelleen.",
+		messagePending:           "Maksu odottaa vahvistusta.",
+	},
+	LangEL: {
+		messageGeneric:           "Δεν ήταν δυνατή η ολοκλήρωση της πληρωμής. Παρακαλούμε δοκιμάστε ξανά.",
+		messageApproved:          "Η πληρωμή ολοκληρώθηκε με επιτυχία.",
+		messageDenied:            "Η πληρωμή απορρίφθηκε. Παρακαλούμε χρησιμοποιήστε άλλη κάρτα ή επικοινωνήστε με την τράπεζά σας.",
+		messageExpiredCard:       "Η κάρτα σας έχει λήξει. Παρακαλούμε χρησιμοποιήστε άλλη κάρτα.",
+		messageInsufficientFunds: "Το διαθέσιμο υπόλοιπο της κάρτας δεν επαρκεί. Παρακαλούμε χρησιμοποιήστε άλλη κάρτα.",
+		messageWrongCardData:     "Τα στοιχεία της κάρτας δεν είναι σωστά. Παρακαλούμε ελέγξτε τα και δοκιμάστε ξανά.",
+		messageAuthentication:    "Ο έλεγχος ταυτότητας απέτυχε. Παρακαλούμε επικοινωνήστε με την τράπεζά σας.",
+		messageCancelled:         "Ακυρώσατε την πληρωμή.",
+		messageTechnical:         "Παρουσιάστηκε τεχνικό σφάλμα. Παρακαλούμε δοκιμάστε ξανά αργότερα.",
+		messagePending:           "Η πληρωμή εκκρεμεί προς επιβεβαίωση.",
+	},
+	LangHU: {
+		messageGeneric:           "A fizetést nem sikerült teljesíteni. Kérjük, próbálja újra.",
+		messageApproved:          "A fizetés sikeresen megtörtént.",
+		messageDenied:            "A fizetést elutasították. Kérjük, használjon másik kártyát, vagy forduljon a bankjához.",
+		messageExpiredCard:       "A kártyája lejárt. Kérjük, használjon másik kártyát.",
+		messageInsufficientFunds: "A kártyán nincs elegendő fedezet. Kérjük, használjon másik kártyát.",
+		messageWrongCardData:     "A kártyaadatok nem helyesek. Kérjük, ellenőrizze őket, és próbálja újra.",
+		messageAuthentication:    "A hitelesítés sikertelen. Kérjük, forduljon a bankjához.",
+		messageCancelled:         "Ön megszakította a fizetést.",
+		messageTechnical:         "Technikai hiba történt. Kérjük, próbálja újra később.",
+		messagePending:           "A fizetés megerősítésre vár.",
+	},
+	LangJA: {
+		messageGeneric:           "お支払いを完了できませんでした。もう一度お試しください。",
+		messageApproved:          "お支払いが正常に完了しました。",
+		messageDenied:            "お支払いが拒否されました。別のカードをご利用いただくか、ご利用の銀行にお問い合わせください。",
+		messageExpiredCard:       "カードの有効期限が切れています。別のカードをご利用ください。",
+		messageInsufficientFunds: "カードの残高が不足しています。別のカードをご利用ください。",
+		messageWrongCardData:     "カード情報が正しくありません。ご確認のうえ、もう一度お試しください。",
+		messageAuthentication:    "本人認証に失敗しました。ご利用の銀行にお問い合わせください。",
+		messageCancelled:         "お支払いをキャンセルしました。",
+		messageTechnical:         "技術的なエラーが発生しました。しばらくしてからもう一度お試しください。",
+		messagePending:           "お支払いは確認待ちです。",
+	},
+	LangLV: {
+		messageGeneric:           "Maksājumu neizdevās pabeigt. Lūdzu, mēģiniet vēlreiz.",
+		messageApproved:          "Maksājums ir veiksmīgi pabeigts.",
+		messageDenied:            "Maksājums tika noraidīts. Lūdzu, izmantojiet citu karti vai sazinieties ar savu banku.",
+		messageExpiredCard:       "Jūsu kartes derīguma termiņš ir beidzies. Lūdzu, izmantojiet citu karti.",
+		messageInsufficientFunds: "Kartē nav pietiekami daudz līdzekļu. Lūdzu, izmantojiet citu karti.",
+		messageWrongCardData:     "Kartes dati nav pareizi. Lūdzu, pārbaudiet tos un mēģiniet vēlreiz.",
+		messageAuthentication:    "Autentifikācija neizdevās. Lūdzu, sazinieties ar savu banku.",
+		messageCancelled:         "Jūs atcēlāt maksājumu.",
+		messageTechnical:         "Radās tehniska kļūda. Lūdzu, mēģiniet vēlreiz vēlāk.",
+		messagePending:           "Maksājums gaida apstiprinājumu.",
+	},
+	LangLT: {
+		messageGeneric:           "Nepavyko atlikti mokėjimo. Bandykite dar kartą.",
+		messageApproved:          "Mokėjimas sėkmingai atliktas.",
+		messageDenied:            "Mokėjimas atmestas. Naudokite kitą kortelę arba susisiekite su savo banku.",
+		messageExpiredCard:       "Jūsų kortelės galiojimo laikas baigėsi. Naudokite kitą kortelę.",
+		messageInsufficientFunds: "Kortelėje nepakanka lėšų. Naudokite kitą kortelę.",
+		messageWrongCardData:     "Kortelės duomenys neteisingi. Patikrinkite juos ir bandykite dar kartą.",
+		messageAuthentication:    "Autentifikacija nepavyko. Susisiekite su savo banku.",
+		messageCancelled:         "Atšaukėte mokėjimą.",
+		messageTechnical:         "Įvyko techninė klaida. Bandykite vėliau.",
+		messagePending:           "Mokėjimas laukia patvirtinimo.",
+	},
+	LangMT: {
+		messageGeneric:           "Il-ħlas ma setax jitlesta. Jekk jogħġbok erġa' pprova.",
+		messageApproved:          "Il-ħlas tlesta b'suċċess.",
+		messageDenied:            "Il-ħlas ġie miċħud. Jekk jogħġbok uża karta oħra jew ikkuntattja lill-bank tiegħek.",
+		messageExpiredCard:       "Il-karta tiegħek skadiet. Jekk jogħġbok uża karta oħra.",
+		messageInsufficientFunds: "Il-karta m'għandhiex biżżejjed fondi. Jekk jogħġbok uża karta oħra.",
+		messageWrongCardData:     "Id-dettalji tal-karta mhumiex korretti. Jekk jogħġbok iċċekkjahom u erġa' pprova.",
+		messageAuthentication:    "L-awtentikazzjoni falliet. Jekk jogħġbok ikkuntattja lill-bank tiegħek.",
+		messageCancelled:         "Ikkanċellajt il-ħlas.",
+		messageTechnical:         "Seħħ żball tekniku. Jekk jogħġbok erġa' pprova aktar tard.",
+		messagePending:           "Il-ħlas qed jistenna konferma.",
+	},
+	LangRO: {
+		messageGeneric:           "Plata nu a putut fi finalizată. Vă rugăm să încercați din nou.",
+		messageApproved:          "Plata a fost finalizată cu succes.",
+		messageDenied:            "Plata a fost refuzată. Vă rugăm să folosiți alt card sau să contactați banca dumneavoastră.",
+		messageExpiredCard:       "Cardul dumneavoastră a expirat. Vă rugăm să folosiți alt card.",
+		messageInsufficientFunds: "Cardul nu are fonduri suficiente. Vă rugăm să folosiți alt card.",
+		messageWrongCardData:     "Datele cardului nu sunt corecte. Vă rugăm să le verificați și să încercați din nou.",
+		messageAuthentication:    "Autentificarea a eșuat. Vă rugăm să contactați banca dumneavoastră.",
+		messageCancelled:         "Ați anulat plata.",
+		messageTechnical:         "A apărut o eroare tehnică. Vă rugăm să încercați din nou mai târziu.",
+		messagePending:           "Plata este în așteptarea confirmării.",
+	},
+	LangRU: {
+		messageGeneric:           "Не удалось завершить платёж. Пожалуйста, попробуйте ещё раз.",
+		messageApproved:          "Платёж успешно завершён.",
+		messageDenied:            "Платёж отклонён. Пожалуйста, используйте другую карту или обратитесь в свой банк.",
+		messageExpiredCard:       "Срок действия вашей карты истёк. Пожалуйста, используйте другую карту.",
+		messageInsufficientFunds: "На карте недостаточно средств. Пожалуйста, используйте другую карту.",
+		messageWrongCardData:     "Данные карты указаны неверно. Пожалуйста, проверьте их и попробуйте ещё раз.",
+		messageAuthentication:    "Не удалось пройти аутентификацию. Пожалуйста, обратитесь в свой банк.",
+		messageCancelled:         "Вы отменили платёж.",
+		messageTechnical:         "Произошла техническая ошибка. Пожалуйста, попробуйте позже.",
+		messagePending:           "Платёж ожидает подтверждения.",
+	},
+	LangSK: {
+		messageGeneric:           "Platbu sa nepodarilo dokončiť. Skúste to prosím znova.",
+		messageApproved:          "Platba bola úspešne dokončená.",
+		messageDenied:            "Platba bola zamietnutá. Použite prosím inú kartu alebo kontaktujte svoju banku.",
+		messageExpiredCard:       "Platnosť vašej karty vypršala. Použite prosím inú kartu.",
+		messageInsufficientFunds: "Na karte nie je dostatok prostriedkov. Použite prosím inú kartu.",
+		messageWrongCardData:     "Údaje o karte nie sú správne. Skontrolujte ich prosím a skúste to znova.",
+		messageAuthentication:    "Overenie sa nepodarilo. Kontaktujte prosím svoju banku.",
+		messageCancelled:         "Zrušili ste platbu.",
+		messageTechnical:         "Vyskytla sa technická chyba. Skúste to prosím neskôr.",
+		messagePending:           "Platba čaká na potvrdenie.",
+	},
+	LangSL: {
+		messageGeneric:           "Plačila ni bilo mogoče dokončati. Poskusite znova.",
+		messageApproved:          "Plačilo je bilo uspešno izvedeno.",
+		messageDenied:            "Plačilo je bilo zavrnjeno. Uporabite drugo kartico ali se obrnite na svojo banko.",
+		messageExpiredCard:       "Vaša kartica je potekla. Uporabite drugo kartico.",
+		messageInsufficientFunds: "Na kartici ni dovolj sredstev. Uporabite drugo kartico.",
+		messageWrongCardData:     "Podatki kartice niso pravilni. Preverite jih in poskusite znova.",
+		messageAuthentication:    "Preverjanje pristnosti ni uspelo. Obrnite se na svojo banko.",
+		messageCancelled:         "Preklicali ste plačilo.",
+		messageTechnical:         "Prišlo je do tehnične napake. Poskusite znova pozneje.",
+		messagePending:           "Plačilo čaka na potrditev.",
+	},
+	LangTR: {
+		messageGeneric:           "Ödeme tamamlanamadı. Lütfen tekrar deneyin.",
+		messageApproved:          "Ödeme başarıyla tamamlandı.",
+		messageDenied:            "Ödeme reddedildi. Lütfen başka bir kart kullanın veya bankanızla iletişime geçin.",
+		messageExpiredCard:       "Kartınızın süresi dolmuş. Lütfen başka bir kart kullanın.",
+		messageInsufficientFunds: "Kartta yeterli bakiye yok. Lütfen başka bir kart kullanın.",
+		messageWrongCardData:     "Kart bilgileri doğru değil. Lütfen kontrol edip tekrar deneyin.",
+		messageAuthentication:    "Kimlik doğrulama başarısız oldu. Lütfen bankanızla iletişime geçin.",
+		messageCancelled:         "Ödemeyi iptal ettiniz.",
+		messageTechnical:         "Teknik bir hata oluştu. Lütfen daha sonra tekrar deneyin.",
+		messagePending:           "Ödeme onay bekliyor.",
+	},
+}
+
+// CustomerMessage returns a message explaining the result of the operation to the customer in the language. It
+// uses English for unknown languages. Frauds are explained as a generic denial to not give any hint to the
+// fraudster.
+func (operation Operation) CustomerMessage(lang Lang) string {
+	messages, ok := customerMessages[lang]
+	if !ok {
+		messages = customerMessages[LangEN]
+	}
+	return messages[operation.messageKey()]
+}
+
+func (operation Operation) messageKey() messageKey {
+	info := operation.Response()
+	switch info.Category {
+	case CategoryApproved:
+		return messageApproved
+
+	case CategoryDenied:
+		switch {
+		case slices.Contains(expiredCardCodes, operation.ResponseCode):
+			return messageExpiredCard
+		case slices.Contains(insufficientFundsCodes, operation.ResponseCode):
+			return messageInsufficientFunds
+		case slices.Contains(wrongCardDataCodes, operation.ResponseCode):
+			return messageWrongCardData
+		}
+		return messageDenied
+
+	case CategoryFraud:
+		return messageDenied
+
+	case CategoryAuthentication:
+		return messageAuthentication
+
+	case CategoryCancelled:
+		return messageCancelled
+
+	case CategoryTechnical, CategoryDuplicate:
+		return messageTechnical
+
+	case CategoryPending:
+		return messagePending
+	}
+	return messageGeneric
+}
@@ -0,0 +1,70 @@
+package redsys
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerMessagesComplete(t *testing.T) {
+	for lang, messages := range customerMessages {
+		for key := messageGeneric; key <= messagePending; key++ {
+			require.NotEmpty(t, messages[key], "lang %s key %d", lang, key)
+		}
+	}
+}
+
+func TestCustomerMessagesAllLangs(t *testing.T) {
+	for _, l := range langs {
+		require.Contains(t, customerMessages, l.lang)
+	}
+}
+
+func TestCustomerMessageCodesDenied(t *testing.T) {
+	var codes []int64
+	codes = append(codes, expiredCardCodes...)
+	codes = append(codes, insufficientFundsCodes...)
+	codes = append(codes, wrongCardDataCodes...)
+	for _, code := range codes {
+		info, ok := LookupResponse(code)
+		require.True(t, ok, code)
+		require.Equal(t, info.Category, CategoryDenied, code)
+	}
+}
+
+func TestCustomerMessage(t *testing.T) {
+	tests := []struct {
+		response int64
+		lang     Lang
+		message  string
+	}{
+		{0, LangEN, "The payment has been completed successfully."},
+		{101, LangEN, "Your card has expired. Please use another card."},
+		{101, LangES, "Tu tarjeta ha caducado. Por favor, utiliza otra tarjeta."},
+		{116, LangEN, "The card does not have enough funds. Please use another card."},
+		{129, LangEN, "The card details are not correct. Please check them and try again."},
+		{190, LangEN, "The payment has been declined. Please use another card or contact your bank."},
+		{9071, LangEN, "Your card has expired. Please use another card."},
+		{9064, LangEN, "The card details are not correct. Please check them and try again."},
+		{9221, LangES, "Los datos de la tarjeta no son correctos. Por favor, revísalos e inténtalo de nuevo."},
+		{184, LangFR, "L'authentification a échoué. Veuillez contacter votre banque."},
+		{9915, LangDE, "Sie haben die Zahlung abgebrochen."},
+		{9051, LangEN, "A technical error has occurred. Please try again later."},
+		{9999, LangIT, "Il pagamento è in attesa di conferma."},
+		{555, LangPT, "Não foi possível concluir o pagamento. Por favor, tente novamente."},
+		{190, Lang("999"), "The payment has been declined. Please use another card or contact your bank."},
+	}
+	for _, test := range tests {
+		operation := Operation{ResponseCode: test.response, Params: Params{RawResponse: fmt.Sprintf("%04d", test.response)}}
+		require.Equal(t, operation.CustomerMessage(test.lang), test.message, test.response)
+	}
+}
+
+func TestCustomerMessageHidesFraud(t *testing.T) {
+	for _, code := range []int64{102, 167, 208, 209} {
+		operation := Operation{ResponseCode: code, Params: Params{RawResponse: fmt.Sprintf("%04d", code)}}
+		require.Equal(t, operation.Response().Category, CategoryFraud)
+		require.Equal(t, operation.CustomerMessage(LangEN), "The payment has been declined. Please use another card or contact your bank.")
+	}
+}
@@ -0,0 +1,160 @@
+package redsys
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultPreAuthorizationValidity is the usual time the bank keeps the money on hold before releasing it
+// automatically if the pre-authorization is not confirmed.
+const DefaultPreAuthorizationValidity = 7 * 24 * time.Hour
+
+// PreAuthorizationState is the state of the money held by a pre-authorization.
+type PreAuthorizationState string
+
+const (
+	// PreAuthorizationHeld means the money is held and waiting for a confirmation or cancellation.
+	PreAuthorizationHeld = PreAuthorizationState("held")
+
+	// PreAuthorizationConfirmed means the money was captured. It is a final state.
+	PreAuthorizationConfirmed = PreAuthorizationState("confirmed")
+
+	// PreAuthorizationCancelled means the money was released to the customer. It is a final state.
+	PreAuthorizationCancelled = PreAuthorizationState("cancelled")
+)
+
+// PreAuthorization tracks the lifecycle of an approved pre-authorization. Store it with the order to confirm or
+// cancel the hold afterwards.
+type PreAuthorization struct {
+	// Order code of the original pre-authorization.
+	Order string
+
+	// Amount held in the minor unit of the merchant currency.
+	Amount int32
+
+	// Time when the pre-authorization was approved.
+	Authorized time.Time
+
+	// Time the hold is valid. By default it will be DefaultPreAuthorizationValidity if empty.
+	Validity time.Duration
+
+	// Current state of the hold.
+	State PreAuthorizationState
+
+	// Amount finally captured when the pre-authorization is confirmed.
+	Captured int32
+}
+
+// NewPreAuthorization starts tracking an approved pre-authorization operation. If the operation has no date, like
+// the server-to-server ones, the current time will be used as the authorization time.
+func NewPreAuthorization(operation Operation) (*PreAuthorization, error) {
+	if operation.Params.TransactionType != TransactionTypePreAuthorization {
+		return nil, fmt.Errorf("operation %q is not a pre-authorization", operation.Params.Order)
+	}
+	if operation.Status != StatusApproved {
+		return nil, fmt.Errorf("pre-authorization %q was not approved", operation.Params.Order)
+	}
+	authorized := operation.Sent
+	if authorized.IsZero() {
+		authorized = time.Now()
+	}
+	return &PreAuthorization{
+		Order:      operation.Params.Order,
+		Amount:     operation.Params.Amount,
+		Authorized: authorized,
+		State:      PreAuthorizationHeld,
+	}, nil
+}
+
+// ExpiresAt returns the time when the bank will release the hold automatically.
+func (preauth *PreAuthorization) ExpiresAt() time.Time {
+	validity := preauth.Validity
+	if validity == 0 {
+		validity = DefaultPreAuthorizationValidity
+	}
+	return preauth.Authorized.Add(validity)
+}
+
+// Expired returns true if the money is still held but the hold has already expired at the specified time.
+func (preauth *PreAuthorization) Expired(now time.Time) bool {
+	return preauth.State == PreAuthorizationHeld && !now.Before(preauth.ExpiresAt())
+}
+
+// ExpiresSoon returns true if the money is still held and the hold expires within the margin of the specified time.
+// It should be used to confirm or cancel the pre-authorization before losing it.
+func (preauth *PreAuthorization) ExpiresSoon(now time.Time, margin time.Duration) bool {
+	return preauth.State == PreAuthorizationHeld && !now.Add(margin).Before(preauth.ExpiresAt())
+}
+
+// PreAuthorizationExpiredError is returned when confirming or cancelling a pre-authorization whose hold was
+// already released by the bank.
+type PreAuthorizationExpiredError struct {
+	// Order code of the pre-authorization.
+	Order string
+
+	// Time when the hold expired.
+	ExpiresAt time.Time
+}
+
+func (err *PreAuthorizationExpiredError) Error() string {
+	return fmt.Sprintf("pre-authorization %q expired at %s", err.Order, err.ExpiresAt.Format(time.RFC3339))
+}
+
+func (preauth *PreAuthorization) transition(now time.Time, to PreAuthorizationState) error {
+	if preauth.State != PreAuthorizationHeld {
+		return fmt.Errorf("cannot change pre-authorization %q from %s to %s", preauth.Order, preauth.State, to)
+	}
+	if preauth.Expired(now) {
+		return &PreAuthorizationExpiredError{Order: preauth.Order, ExpiresAt: preauth.ExpiresAt()}
+	}
+	return nil
+}
+
+// ConfirmPreAuthorization captures the money held by the pre-authorization. The amount can be lower than the held
+// one but never higher. The state of the pre-authorization is updated when the bank approves the confirmation. If
+// the hold already expired a *PreAuthorizationExpiredError is returned without sending anything to the bank.
+func (client *Client) ConfirmPreAuthorization(ctx context.Context, merchant Merchant, preauth *PreAuthorization, amount int32) (Operation, error) {
+	if err := preauth.transition(client.currentTime(), PreAuthorizationConfirmed); err != nil {
+		return Operation{}, err
+	}
+	if amount <= 0 || amount > preauth.Amount {
+		return Operation{}, fmt.Errorf("invalid confirmation amount %d for pre-authorization %q of %d", amount, preauth.Order, preauth.Amount)
+	}
+
+	operation, err := client.Send(ctx, merchant, Request{
+		TransactionType: TransactionTypePreAuthorizationConfirmation,
+		Order:           preauth.Order,
+		Amount:          amount,
+	})
+	if err != nil {
+		return Operation{}, err
+	}
+	if operation.Status == StatusApproved {
+		preauth.State = PreAuthorizationConfirmed
+		preauth.Captured = amount
+	}
+	return operation, nil
+}
+
+// CancelPreAuthorization releases the money held by the pre-authorization. The state of the pre-authorization is
+// updated when the bank approves the cancellation. If the hold already expired a *PreAuthorizationExpiredError is
+// returned without sending anything to the bank.
+func (client *Client) CancelPreAuthorization(ctx context.Context, merchant Merchant, preauth *PreAuthorization) (Operation, error) {
+	if err := preauth.transition(client.currentTime(), PreAuthorizationCancelled); err != nil {
+		return Operation{}, err
+	}
+
+	operation, err := client.Send(ctx, merchant, Request{
+		TransactionType: TransactionTypePreAuthorizationCancellation,
+		Order:           preauth.Order,
+		Amount:          preauth.Amount,
+	})
+	if err != nil {
+		return Operation{}, err
+	}
+	if operation.Status == StatusApproved {
+		preauth.State = PreAuthorizationCancelled
+	}
+	return operation, nil
+}
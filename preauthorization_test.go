@@ -0,0 +1,130 @@
+package redsys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPreAuthorization() *PreAuthorization {
+	return &PreAuthorization{
+		Order:      "00011234abcd",
+		Amount:     1000,
+		Authorized: time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+		State:      PreAuthorizationHeld,
+	}
+}
+
+// testPreAuthorizationNow is a time inside the validity of testPreAuthorization.
+func testPreAuthorizationNow() time.Time {
+	return time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+}
+
+func TestNewPreAuthorization(t *testing.T) {
+	operation := Operation{
+		Status: StatusApproved,
+		Sent:   time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+		Params: Params{Order: "00011234abcd", Amount: 1000, TransactionType: TransactionTypePreAuthorization},
+	}
+	preauth, err := NewPreAuthorization(operation)
+	require.NoError(t, err)
+
+	require.Equal(t, preauth.State, PreAuthorizationHeld)
+	require.EqualValues(t, preauth.Amount, 1000)
+	require.Equal(t, preauth.ExpiresAt(), time.Date(2024, time.March, 8, 10, 0, 0, 0, time.UTC))
+}
+
+func TestNewPreAuthorizationNotApproved(t *testing.T) {
+	operation := Operation{
+		Status: StatusCancelled,
+		Params: Params{Order: "00011234abcd", TransactionType: TransactionTypePreAuthorization},
+	}
+	_, err := NewPreAuthorization(operation)
+	require.EqualError(t, err, `pre-authorization "00011234abcd" was not approved`)
+}
+
+func TestPreAuthorizationExpiresSoon(t *testing.T) {
+	preauth := testPreAuthorization()
+
+	require.False(t, preauth.ExpiresSoon(time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC), 24*time.Hour))
+	require.True(t, preauth.ExpiresSoon(time.Date(2024, time.March, 7, 12, 0, 0, 0, time.UTC), 24*time.Hour))
+	require.False(t, preauth.Expired(time.Date(2024, time.March, 7, 12, 0, 0, 0, time.UTC)))
+	require.True(t, preauth.Expired(time.Date(2024, time.March, 8, 10, 0, 0, 0, time.UTC)))
+}
+
+func TestConfirmPreAuthorization(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		require.Equal(t, params["Ds_Merchant_TransactionType"], float64(2))
+		require.Equal(t, params["Ds_Merchant_Amount"], float64(800))
+		return map[string]interface{}{
+			"Ds_Order":           "00011234abcd",
+			"Ds_Response":        "0900",
+			"Ds_Amount":          "800",
+			"Ds_TransactionType": "2",
+		}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL, now: testPreAuthorizationNow}
+	preauth := testPreAuthorization()
+
+	operation, err := client.ConfirmPreAuthorization(context.Background(), testMerchant(), preauth, 800)
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusApproved)
+	require.Equal(t, preauth.State, PreAuthorizationConfirmed)
+	require.EqualValues(t, preauth.Captured, 800)
+	require.False(t, preauth.ExpiresSoon(preauth.ExpiresAt(), time.Hour))
+
+	_, err = client.ConfirmPreAuthorization(context.Background(), testMerchant(), preauth, 800)
+	require.EqualError(t, err, `cannot change pre-authorization "00011234abcd" from confirmed to confirmed`)
+
+	_, err = client.CancelPreAuthorization(context.Background(), testMerchant(), preauth)
+	require.EqualError(t, err, `cannot change pre-authorization "00011234abcd" from confirmed to cancelled`)
+}
+
+func TestConfirmPreAuthorizationHigherAmount(t *testing.T) {
+	client := &Client{now: testPreAuthorizationNow}
+	_, err := client.ConfirmPreAuthorization(context.Background(), testMerchant(), testPreAuthorization(), 1200)
+	require.EqualError(t, err, `invalid confirmation amount 1200 for pre-authorization "00011234abcd" of 1000`)
+}
+
+func TestCancelPreAuthorization(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		require.Equal(t, params["Ds_Merchant_TransactionType"], float64(9))
+		require.Equal(t, params["Ds_Merchant_Amount"], float64(1000))
+		return map[string]interface{}{
+			"Ds_Order":           "00011234abcd",
+			"Ds_Response":        "0400",
+			"Ds_TransactionType": "9",
+		}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL, now: testPreAuthorizationNow}
+	preauth := testPreAuthorization()
+
+	operation, err := client.CancelPreAuthorization(context.Background(), testMerchant(), preauth)
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusApproved)
+	require.Equal(t, preauth.State, PreAuthorizationCancelled)
+
+	_, err = client.ConfirmPreAuthorization(context.Background(), testMerchant(), preauth, 1000)
+	require.EqualError(t, err, `cannot change pre-authorization "00011234abcd" from cancelled to confirmed`)
+}
+
+func TestPreAuthorizationExpired(t *testing.T) {
+	client := &Client{
+		now: func() time.Time { return time.Date(2024, time.March, 8, 10, 0, 0, 0, time.UTC) },
+	}
+	preauth := testPreAuthorization()
+
+	_, err := client.ConfirmPreAuthorization(context.Background(), testMerchant(), preauth, 800)
+	var expired *PreAuthorizationExpiredError
+	require.ErrorAs(t, err, &expired)
+	require.Equal(t, expired.Order, "00011234abcd")
+	require.Equal(t, expired.ExpiresAt, time.Date(2024, time.March, 8, 10, 0, 0, 0, time.UTC))
+	require.EqualError(t, err, `pre-authorization "00011234abcd" expired at 2024-03-08T10:00:00Z`)
+
+	_, err = client.CancelPreAuthorization(context.Background(), testMerchant(), preauth)
+	require.ErrorAs(t, err, &expired)
+
+	require.Equal(t, preauth.State, PreAuthorizationHeld)
+}
@@ -0,0 +1,229 @@
+package redsys
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const pathQuery = "/apl02/services/SerClsWSConsulta"
+
+// queryStateFinal is the Ds_State of the operations that are already finished.
+const queryStateFinal = "F"
+
+// QueryClient asks the consultation webservice of the bank about the operations of the merchant. It is useful to
+// find out what happened to an order when the notification never arrives.
+//
+// The responses of the consultation webservice are not signed by the bank and they are not HMAC-verified like the
+// notifications or the REST responses; they are only trusted because of the TLS connection to the bank, so do not
+// point BaseURL to anything else outside of tests.
+type QueryClient struct {
+	// HTTP client to use in the requests. By default it will be http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Base URL of the bank. By default it will be the production or debug server depending on the merchant.
+	BaseURL string
+}
+
+type queryTransaction struct {
+	XMLName      xml.Name `xml:"Transaction"`
+	MerchantCode string   `xml:"Ds_MerchantCode"`
+	Terminal     int64    `xml:"Ds_Terminal"`
+	Order        string   `xml:"Ds_Order"`
+}
+
+type queryMonitor struct {
+	XMLName      xml.Name `xml:"Monitor"`
+	MerchantCode string   `xml:"Ds_MerchantCode"`
+	Terminal     int64    `xml:"Ds_Terminal"`
+	From         string   `xml:"Ds_Fecha_inicio"`
+	To           string   `xml:"Ds_Fecha_fin"`
+}
+
+type queryMessages struct {
+	Responses []queryResponse `xml:"Version>Message>Response"`
+	ErrorCode string          `xml:"Version>Message>ErrorMsg>Ds_ErrorCode"`
+}
+
+type queryResponse struct {
+	MerchantCode    string `xml:"Ds_MerchantCode"`
+	Terminal        string `xml:"Ds_Terminal"`
+	Order           string `xml:"Ds_Order"`
+	TransactionType string `xml:"Ds_TransactionType"`
+	Date            string `xml:"Ds_Date"`
+	Hour            string `xml:"Ds_Hour"`
+	Amount          string `xml:"Ds_Amount"`
+	Currency        string `xml:"Ds_Currency"`
+	Response        string `xml:"Ds_Response"`
+	AuthCode        string `xml:"Ds_AuthorisationCode"`
+	CardCountry     string `xml:"Ds_Card_Country"`
+	CardType        string `xml:"Ds_Card_Type"`
+	MerchantData    string `xml:"Ds_MerchantData"`
+	State           string `xml:"Ds_State"`
+}
+
+type queryEnvelope struct {
+	Body struct {
+		Response struct {
+			Return string `xml:"consultaOperacionesReturn"`
+		} `xml:"consultaOperacionesResponse"`
+	} `xml:"Body"`
+}
+
+// Query returns all the operations of the order, e.g. the authorization and its refunds. It returns an empty list
+// if the bank does not know about the order.
+func (client *QueryClient) Query(ctx context.Context, merchant Merchant, order string) ([]Operation, error) {
+	if !reOrder.MatchString(order) {
+		return nil, fmt.Errorf("invalid order format %q", order)
+	}
+	return client.do(ctx, merchant, order, queryTransaction{
+		MerchantCode: merchant.Code,
+		Terminal:     merchant.Terminal,
+		Order:        order,
+	})
+}
+
+// QueryRange returns all the operations of the merchant terminal between the two dates.
+func (client *QueryClient) QueryRange(ctx context.Context, merchant Merchant, from, to time.Time) ([]Operation, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid query range from %s to %s", from, to)
+	}
+	// Range queries do not have an order to derive the key, the merchant code is used instead.
+	return client.do(ctx, merchant, merchant.Code, queryMonitor{
+		MerchantCode: merchant.Code,
+		Terminal:     merchant.Terminal,
+		From:         from.Format("2006-01-02 15:04:05"),
+		To:           to.Format("2006-01-02 15:04:05"),
+	})
+}
+
+func (client *QueryClient) do(ctx context.Context, merchant Merchant, key string, query interface{}) ([]Operation, error) {
+	queryXML, err := xml.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal query: %v", err)
+	}
+	version := fmt.Sprintf(`<Version Ds_Version="0.0"><Message>%s</Message></Version>`, queryXML)
+	signature, err := merchant.signer().Sign(ctx, key, version)
+	if err != nil {
+		return nil, fmt.Errorf("%v", err)
+	}
+	request := fmt.Sprintf("<Messages>%s<Signature>%s</Signature><SignatureVersion>HMAC_SHA256_V1</SignatureVersion></Messages>", version, base64.StdEncoding.EncodeToString(signature))
+
+	var body bytes.Buffer
+	body.WriteString(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:web="http://webservices.apl02.redsys.es">`)
+	body.WriteString(`<soapenv:Header/><soapenv:Body><web:consultaOperaciones><cadenaXML>`)
+	if err := xml.EscapeText(&body, []byte(request)); err != nil {
+		return nil, fmt.Errorf("cannot escape request: %v", err)
+	}
+	body.WriteString(`</cadenaXML></web:consultaOperaciones></soapenv:Body></soapenv:Envelope>`)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURLOrDefault(client.BaseURL, merchant)+pathQuery, &body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot prepare request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	httpReq.Header.Set("SOAPAction", "")
+	resp, err := httpClientOrDefault(client.HTTPClient).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("cannot send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var envelope queryEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %v", err)
+	}
+	var reply queryMessages
+	if err := xml.Unmarshal([]byte(envelope.Body.Response.Return), &reply); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %v", err)
+	}
+	switch reply.ErrorCode {
+	case "":
+	case "XML0024":
+		// There are no operations for the query.
+		return nil, nil
+	default:
+		return nil, &SISError{Code: reply.ErrorCode}
+	}
+
+	var operations []Operation
+	for _, response := range reply.Responses {
+		params := Params{
+			Order:              response.Order,
+			MerchantCode:       response.MerchantCode,
+			RawTerminal:        response.Terminal,
+			RawResponse:        response.Response,
+			RawAmount:          response.Amount,
+			RawCurrency:        response.Currency,
+			RawTransactionType: response.TransactionType,
+			Date:               queryDate(response.Date),
+			Time:               queryTime(response.Hour),
+			AuthCode:           response.AuthCode,
+			Country:            response.CardCountry,
+			CardType:           response.CardType,
+			Data:               response.MerchantData,
+			State:              response.State,
+		}
+		if err := params.parseRaw(); err != nil {
+			return nil, err
+		}
+		operation, err := queryOperation(params)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+	return operations, nil
+}
+
+// queryOperation classifies the operations of the consultation webservice. The operations that are not final yet
+// are pending, they are not classified because they may not even have a response code.
+func queryOperation(params Params) (Operation, error) {
+	if params.IsFinal() {
+		return newServerOperation(params)
+	}
+	operation := Operation{
+		Params:       params,
+		ResponseCode: params.Response,
+		Outcome:      StatusPending,
+	}
+	if params.Date != "" {
+		var err error
+		operation.Sent, err = params.sent()
+		if err != nil {
+			return Operation{}, err
+		}
+	}
+	return operation, nil
+}
+
+// IsFinal returns true if the bank will not change the operation anymore. Only the consultation webservice reports
+// operations that are not final yet.
+func (params Params) IsFinal() bool {
+	return params.State == "" || params.State == queryStateFinal
+}
+
+// queryDate converts the ISO dates of the consultation webservice to the format of the notifications.
+func queryDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("02/01/2006")
+}
+
+// queryTime removes the seconds that the consultation webservice sends.
+func queryTime(hour string) string {
+	if parts := strings.Split(hour, ":"); len(parts) == 3 {
+		return parts[0] + ":" + parts[1]
+	}
+	return hour
+}
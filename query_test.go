@@ -0,0 +1,141 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var reQueryVersion = regexp.MustCompile(`<Version Ds_Version="0.0">.*</Version>`)
+
+// newQueryServer emulates the consultation webservice of the bank. It verifies the signature of the query with the
+// key and returns the messages built by the reply function.
+func newQueryServer(t *testing.T, key string, reply func(query string) string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, r.URL.Path, "/apl02/services/SerClsWSConsulta")
+
+		var envelope struct {
+			Body struct {
+				Request struct {
+					Input string `xml:"cadenaXML"`
+				} `xml:"consultaOperaciones"`
+			} `xml:"Body"`
+		}
+		require.NoError(t, xml.NewDecoder(r.Body).Decode(&envelope))
+		request := envelope.Body.Request.Input
+
+		version := reQueryVersion.FindString(request)
+		signature, err := sign(testSecret, key, version)
+		require.NoError(t, err)
+		require.Contains(t, request, fmt.Sprintf("<Signature>%s</Signature>", base64.StdEncoding.EncodeToString(signature)))
+
+		var escaped strings.Builder
+		require.NoError(t, xml.EscapeText(&escaped, []byte(reply(version))))
+		fmt.Fprintf(w, `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><ns1:consultaOperacionesResponse xmlns:ns1="http://webservices.apl02.redsys.es"><consultaOperacionesReturn>%s</consultaOperacionesReturn></ns1:consultaOperacionesResponse></soapenv:Body></soapenv:Envelope>`, escaped.String())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestQuery(t *testing.T) {
+	server := newQueryServer(t, "00011234abcd", func(query string) string {
+		require.Equal(t, query, `<Version Ds_Version="0.0"><Message><Transaction><Ds_MerchantCode>123456789</Ds_MerchantCode><Ds_Terminal>1</Ds_Terminal><Ds_Order>00011234abcd</Ds_Order></Transaction></Message></Version>`)
+		return `<Messages><Version Ds_Version="0.0"><Message>` +
+			`<Response Ds_Version="0.0"><Ds_MerchantCode>123456789</Ds_MerchantCode><Ds_Terminal>1</Ds_Terminal><Ds_Order>00011234abcd</Ds_Order><Ds_TransactionType>0</Ds_TransactionType><Ds_Date>2024-03-01</Ds_Date><Ds_Hour>10:15:32</Ds_Hour><Ds_Amount>1000</Ds_Amount><Ds_Currency>978</Ds_Currency><Ds_Response>0000</Ds_Response><Ds_AuthorisationCode>123456</Ds_AuthorisationCode><Ds_State>F</Ds_State></Response>` +
+			`<Response Ds_Version="0.0"><Ds_MerchantCode>123456789</Ds_MerchantCode><Ds_Terminal>1</Ds_Terminal><Ds_Order>00011234abcd</Ds_Order><Ds_TransactionType>3</Ds_TransactionType><Ds_Date>2024-03-02</Ds_Date><Ds_Hour>09:00:00</Ds_Hour><Ds_Amount>400</Ds_Amount><Ds_Currency>978</Ds_Currency><Ds_Response>0900</Ds_Response><Ds_State>F</Ds_State></Response>` +
+			`</Message></Version></Messages>`
+	})
+	client := &QueryClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operations, err := client.Query(context.Background(), testMerchant(), "00011234abcd")
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+
+	require.Equal(t, operations[0].Status, StatusApproved)
+	require.Equal(t, operations[0].Params.TransactionType, TransactionTypeSimpleAuthorization)
+	require.EqualValues(t, operations[0].Params.Amount, 1000)
+	require.Equal(t, operations[0].Params.AuthCode, "123456")
+	require.True(t, operations[0].Params.IsFinal())
+	require.Equal(t, operations[0].Sent, time.Date(2024, time.March, 1, 10, 15, 0, 0, time.UTC))
+
+	require.Equal(t, operations[1].Status, StatusApproved)
+	require.Equal(t, operations[1].Params.TransactionType, TransactionTypeRefund)
+	require.EqualValues(t, operations[1].ResponseCode, 900)
+
+	require.EqualValues(t, Refundable("00011234abcd", operations), 600)
+}
+
+func TestQueryNotFinal(t *testing.T) {
+	server := newQueryServer(t, "00011234abcd", func(query string) string {
+		return `<Messages><Version Ds_Version="0.0"><Message>` +
+			`<Response Ds_Version="0.0"><Ds_MerchantCode>123456789</Ds_MerchantCode><Ds_Terminal>1</Ds_Terminal><Ds_Order>00011234abcd</Ds_Order><Ds_TransactionType>0</Ds_TransactionType><Ds_Date>2024-03-01</Ds_Date><Ds_Hour>10:15:32</Ds_Hour><Ds_Amount>1000</Ds_Amount><Ds_Currency>978</Ds_Currency><Ds_State>P</Ds_State></Response>` +
+			`</Message></Version></Messages>`
+	})
+	client := &QueryClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operations, err := client.Query(context.Background(), testMerchant(), "00011234abcd")
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+
+	require.False(t, operations[0].Params.IsFinal())
+	require.Equal(t, operations[0].Params.State, "P")
+	require.Equal(t, operations[0].Status, StatusUnknown)
+	require.Equal(t, operations[0].Outcome, StatusPending)
+	require.Equal(t, operations[0].Sent, time.Date(2024, time.March, 1, 10, 15, 0, 0, time.UTC))
+}
+
+func TestQueryUnknownOrder(t *testing.T) {
+	server := newQueryServer(t, "00011234abcd", func(query string) string {
+		return `<Messages><Version Ds_Version="0.0"><Message><ErrorMsg><Ds_ErrorCode>XML0024</Ds_ErrorCode></ErrorMsg></Message></Version></Messages>`
+	})
+	client := &QueryClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operations, err := client.Query(context.Background(), testMerchant(), "00011234abcd")
+	require.NoError(t, err)
+	require.Empty(t, operations)
+}
+
+func TestQueryError(t *testing.T) {
+	server := newQueryServer(t, "00011234abcd", func(query string) string {
+		return `<Messages><Version Ds_Version="0.0"><Message><ErrorMsg><Ds_ErrorCode>XML0010</Ds_ErrorCode></ErrorMsg></Message></Version></Messages>`
+	})
+	client := &QueryClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	_, err := client.Query(context.Background(), testMerchant(), "00011234abcd")
+	var sisErr *SISError
+	require.True(t, errors.As(err, &sisErr))
+	require.Equal(t, sisErr.Code, "XML0010")
+}
+
+func TestQueryRange(t *testing.T) {
+	server := newQueryServer(t, "123456789", func(query string) string {
+		require.Equal(t, query, `<Version Ds_Version="0.0"><Message><Monitor><Ds_MerchantCode>123456789</Ds_MerchantCode><Ds_Terminal>1</Ds_Terminal><Ds_Fecha_inicio>2024-03-01 00:00:00</Ds_Fecha_inicio><Ds_Fecha_fin>2024-03-02 00:00:00</Ds_Fecha_fin></Monitor></Message></Version>`)
+		return `<Messages><Version Ds_Version="0.0"><Message>` +
+			`<Response Ds_Version="0.0"><Ds_Order>00011234abcd</Ds_Order><Ds_TransactionType>0</Ds_TransactionType><Ds_Date>2024-03-01</Ds_Date><Ds_Hour>10:15:32</Ds_Hour><Ds_Amount>1000</Ds_Amount><Ds_Currency>978</Ds_Currency><Ds_Response>0190</Ds_Response></Response>` +
+			`</Message></Version></Messages>`
+	})
+	client := &QueryClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	operations, err := client.QueryRange(context.Background(), testMerchant(), from, from.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+	require.Equal(t, operations[0].Status, StatusCancelled)
+}
+
+func TestQueryRangeInvalid(t *testing.T) {
+	client := new(QueryClient)
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	_, err := client.QueryRange(context.Background(), testMerchant(), from, from)
+	require.ErrorContains(t, err, "invalid query range")
+}
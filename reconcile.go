@@ -0,0 +1,123 @@
+package redsys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultGracePeriod is the time a pending order waits for its notification before asking the bank about it.
+const DefaultGracePeriod = 30 * time.Minute
+
+// OperationFunc receives the final operation of an order, either from a notification of the bank or from the
+// reconciliation of a missing notification.
+type OperationFunc func(ctx context.Context, operation Operation) error
+
+// PendingOrder is an order of the caller that has not received its notification yet.
+type PendingOrder struct {
+	// Order code sent to the bank.
+	Order string
+
+	// Time when the payment was signed.
+	Created time.Time
+}
+
+// PendingStore lists the orders of the caller that are waiting for a notification.
+type PendingStore interface {
+	// PendingOrders returns the orders created before the time that are still waiting for a notification.
+	PendingOrders(ctx context.Context, before time.Time) ([]PendingOrder, error)
+}
+
+// OrderQuerier returns the operations the bank knows about an order. It is implemented by *QueryClient.
+type OrderQuerier interface {
+	Query(ctx context.Context, merchant Merchant, order string) ([]Operation, error)
+}
+
+// Reconciler asks the bank about the orders whose notification never arrived and emits their operations as if
+// the notification had been received.
+//
+// The emitted operations are not signed by the bank: they are trusted because of the TLS connection to the
+// consultation webservice. They are marked as Reconciled so the callbacks can tell them apart from the verified
+// notifications.
+type Reconciler struct {
+	// Merchant of the orders.
+	Merchant Merchant
+
+	// Querier of the operations, usually a *QueryClient.
+	Querier OrderQuerier
+
+	// Store of the pending orders.
+	Store PendingStore
+
+	// Time to wait for the notification before asking the bank. By default it will be DefaultGracePeriod if empty.
+	GracePeriod time.Duration
+
+	// Notify receives the operations of the reconciled orders. It should be the same function that processes
+	// the notifications so the order is resolved and not listed as pending again.
+	Notify OperationFunc
+
+	now func() time.Time
+}
+
+// Run reconciles the pending orders once. It should be called periodically. Orders the bank does not know about
+// yet, or whose payment has not finished, are left pending for the next run. An error in one order does not stop the rest; all of them are returned
+// together at the end.
+func (reconciler *Reconciler) Run(ctx context.Context) error {
+	now := time.Now
+	if reconciler.now != nil {
+		now = reconciler.now
+	}
+	grace := reconciler.GracePeriod
+	if grace == 0 {
+		grace = DefaultGracePeriod
+	}
+	before := now().Add(-grace)
+
+	pending, err := reconciler.Store.PendingOrders(ctx, before)
+	if err != nil {
+		return fmt.Errorf("cannot list pending orders: %v", err)
+	}
+
+	var errs []error
+	for _, order := range pending {
+		if order.Created.After(before) {
+			continue
+		}
+		if err := reconciler.reconcile(ctx, order.Order); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (reconciler *Reconciler) reconcile(ctx context.Context, order string) error {
+	operations, err := reconciler.Querier.Query(ctx, reconciler.Merchant, order)
+	if err != nil {
+		return fmt.Errorf("cannot query order %q: %w", order, err)
+	}
+
+	// The notification belongs to the payment itself, other operations like refunds are ignored.
+	var payment *Operation
+	for i, operation := range operations {
+		switch operation.Params.TransactionType {
+		case TransactionTypeSimpleAuthorization, TransactionTypePreAuthorization:
+			if payment == nil || !operation.Sent.Before(payment.Sent) {
+				payment = &operations[i]
+			}
+		}
+	}
+	if payment == nil {
+		return nil
+	}
+	// Operations that are still in progress, or without a response of the bank, are retried in the next run.
+	if !payment.Params.IsFinal() || payment.Params.RawResponse == "" {
+		return nil
+	}
+
+	payment.Reconciled = true
+	if err := reconciler.Notify(ctx, *payment); err != nil {
+		return fmt.Errorf("cannot notify order %q: %w", order, err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package redsys
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPendingStore struct {
+	orders []PendingOrder
+	before time.Time
+}
+
+func (store *testPendingStore) PendingOrders(ctx context.Context, before time.Time) ([]PendingOrder, error) {
+	store.before = before
+	return store.orders, nil
+}
+
+type testQuerier map[string][]Operation
+
+func (querier testQuerier) Query(ctx context.Context, merchant Merchant, order string) ([]Operation, error) {
+	operations, ok := querier[order]
+	if !ok {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return operations, nil
+}
+
+func TestReconcilerRun(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	store := &testPendingStore{
+		orders: []PendingOrder{
+			{Order: "00011234abcd", Created: now.Add(-time.Hour)},
+			{Order: "00021234abcd", Created: now.Add(-time.Hour)},
+			{Order: "00031234abcd", Created: now.Add(-time.Minute)},
+		},
+	}
+	querier := testQuerier{
+		"00011234abcd": {
+			{
+				Status: StatusApproved,
+				Sent:   now.Add(-time.Hour),
+				Params: Params{Order: "00011234abcd", RawResponse: "0000", TransactionType: TransactionTypeSimpleAuthorization},
+			},
+			{
+				Status: StatusApproved,
+				Sent:   now.Add(-time.Minute),
+				Params: Params{Order: "00011234abcd", RawResponse: "0900", TransactionType: TransactionTypeRefund},
+			},
+		},
+		"00021234abcd": nil,
+	}
+	var notified []Operation
+	reconciler := &Reconciler{
+		Merchant: testMerchant(),
+		Querier:  querier,
+		Store:    store,
+		Notify: func(ctx context.Context, operation Operation) error {
+			notified = append(notified, operation)
+			return nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	require.NoError(t, reconciler.Run(context.Background()))
+
+	require.Equal(t, store.before, now.Add(-DefaultGracePeriod))
+	require.Len(t, notified, 1)
+	require.Equal(t, notified[0].Params.Order, "00011234abcd")
+	require.Equal(t, notified[0].Params.TransactionType, TransactionTypeSimpleAuthorization)
+	require.True(t, notified[0].Reconciled)
+}
+
+func TestReconcilerRunUnfinished(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	store := &testPendingStore{
+		orders: []PendingOrder{
+			{Order: "00011234abcd", Created: now.Add(-time.Hour)},
+			{Order: "00021234abcd", Created: now.Add(-time.Hour)},
+		},
+	}
+	querier := testQuerier{
+		"00011234abcd": {
+			{
+				Outcome: StatusPending,
+				Sent:    now.Add(-time.Hour),
+				Params:  Params{Order: "00011234abcd", State: "P", TransactionType: TransactionTypeSimpleAuthorization},
+			},
+		},
+		"00021234abcd": {
+			{
+				Status: StatusApproved,
+				Sent:   now.Add(-time.Hour),
+				Params: Params{Order: "00021234abcd", State: "F", TransactionType: TransactionTypeSimpleAuthorization},
+			},
+		},
+	}
+	reconciler := &Reconciler{
+		Merchant: testMerchant(),
+		Querier:  querier,
+		Store:    store,
+		Notify: func(ctx context.Context, operation Operation) error {
+			t.Fatalf("unexpected notification of order %q", operation.Params.Order)
+			return nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	require.NoError(t, reconciler.Run(context.Background()))
+}
+
+func TestReconcilerRunErrors(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	store := &testPendingStore{
+		orders: []PendingOrder{
+			{Order: "00011234abcd", Created: now.Add(-time.Hour)},
+			{Order: "00021234abcd", Created: now.Add(-time.Hour)},
+			{Order: "00031234abcd", Created: now.Add(-time.Hour)},
+		},
+	}
+	querier := testQuerier{
+		"00011234abcd": {
+			{Status: StatusCancelled, Params: Params{Order: "00011234abcd", RawResponse: "9915"}},
+		},
+		"00031234abcd": {
+			{Status: StatusApproved, Params: Params{Order: "00031234abcd", RawResponse: "0000"}},
+		},
+	}
+	var notified []string
+	reconciler := &Reconciler{
+		Merchant:    testMerchant(),
+		Querier:     querier,
+		Store:       store,
+		GracePeriod: time.Hour,
+		Notify: func(ctx context.Context, operation Operation) error {
+			if operation.Status == StatusCancelled {
+				return fmt.Errorf("database unavailable")
+			}
+			notified = append(notified, operation.Params.Order)
+			return nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	err := reconciler.Run(context.Background())
+	require.EqualError(t, err, `cannot notify order "00011234abcd": database unavailable`+"\n"+`cannot query order "00021234abcd": connection refused`)
+	require.Equal(t, notified, []string{"00031234abcd"})
+}
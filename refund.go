@@ -0,0 +1,49 @@
+package redsys
+
+import (
+	"context"
+	"fmt"
+)
+
+// Refund returns to the customer the amount of a previously approved order. The amount can be lower than the
+// original one to make a partial refund; several partial refunds can be sent for the same order.
+//
+// If the history of operations of the order is provided the refund will be rejected before sending it when the
+// amount exceeds what remains captured after the previous refunds.
+func (client *Client) Refund(ctx context.Context, merchant Merchant, order string, amount int32, history ...Operation) (Operation, error) {
+	return client.refund(ctx, merchant, Request{
+		TransactionType: TransactionTypeRefund,
+		Order:           order,
+		Amount:          amount,
+	}, history)
+}
+
+func (client *Client) refund(ctx context.Context, merchant Merchant, req Request, history []Operation) (Operation, error) {
+	if req.Amount <= 0 {
+		return Operation{}, fmt.Errorf("invalid refund amount %d", req.Amount)
+	}
+	if len(history) > 0 {
+		if remaining := Refundable(req.Order, history); req.Amount > remaining {
+			return Operation{}, fmt.Errorf("refund amount %d exceeds the remaining captured amount %d", req.Amount, remaining)
+		}
+	}
+	return client.Send(ctx, merchant, req)
+}
+
+// Refundable returns the captured amount of the order that has not been refunded yet according to its history of
+// operations. Operations of other orders and operations not approved by the bank are ignored.
+func Refundable(order string, history []Operation) int32 {
+	var remaining int32
+	for _, operation := range history {
+		if operation.Params.Order != order || operation.Status != StatusApproved {
+			continue
+		}
+		switch operation.Params.TransactionType {
+		case TransactionTypeSimpleAuthorization, TransactionTypePreAuthorizationConfirmation:
+			remaining += operation.Params.Amount
+		case TransactionTypeRefund:
+			remaining -= operation.Params.Amount
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,73 @@
+package redsys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundPartial(t *testing.T) {
+	server := newRESTServer(t, func(path string, params map[string]interface{}) map[string]interface{} {
+		require.Equal(t, params["Ds_Merchant_TransactionType"], float64(3))
+		require.Equal(t, params["Ds_Merchant_Amount"], float64(400))
+		return map[string]interface{}{
+			"Ds_Order":           "00011234abcd",
+			"Ds_Response":        "0900",
+			"Ds_Amount":          "400",
+			"Ds_TransactionType": "3",
+		}
+	})
+	client := &Client{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operation, err := client.Refund(context.Background(), testMerchant(), "00011234abcd", 400)
+	require.NoError(t, err)
+
+	require.Equal(t, operation.Status, StatusApproved)
+	require.EqualValues(t, operation.Params.Amount, 400)
+	require.Equal(t, operation.Params.TransactionType, TransactionTypeRefund)
+}
+
+func TestRefundExceedsHistory(t *testing.T) {
+	history := []Operation{
+		{
+			Status: StatusApproved,
+			Params: Params{Order: "00011234abcd", Amount: 1000, TransactionType: TransactionTypeSimpleAuthorization},
+		},
+		{
+			Status: StatusApproved,
+			Params: Params{Order: "00011234abcd", Amount: 700, TransactionType: TransactionTypeRefund},
+		},
+	}
+	client := new(Client)
+	_, err := client.Refund(context.Background(), testMerchant(), "00011234abcd", 400, history...)
+	require.EqualError(t, err, "refund amount 400 exceeds the remaining captured amount 300")
+}
+
+func TestRefundInvalidAmount(t *testing.T) {
+	client := new(Client)
+	_, err := client.Refund(context.Background(), testMerchant(), "00011234abcd", 0)
+	require.EqualError(t, err, "invalid refund amount 0")
+}
+
+func TestRefundable(t *testing.T) {
+	history := []Operation{
+		{
+			Status: StatusApproved,
+			Params: Params{Order: "00011234abcd", Amount: 1000, TransactionType: TransactionTypeSimpleAuthorization},
+		},
+		{
+			Status: StatusCancelled,
+			Params: Params{Order: "00011234abcd", Amount: 500, TransactionType: TransactionTypeRefund},
+		},
+		{
+			Status: StatusApproved,
+			Params: Params{Order: "00019999abcd", Amount: 500, TransactionType: TransactionTypeRefund},
+		},
+		{
+			Status: StatusApproved,
+			Params: Params{Order: "00011234abcd", Amount: 250, TransactionType: TransactionTypeRefund},
+		},
+	}
+	require.EqualValues(t, Refundable("00011234abcd", history), 750)
+}
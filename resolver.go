@@ -0,0 +1,55 @@
+package redsys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownMerchant is returned when the secret of the merchant of a notification cannot be found.
+var ErrUnknownMerchant = errors.New("unknown merchant")
+
+// SecretResolver finds the secret of a merchant terminal to verify its notifications.
+type SecretResolver interface {
+	// ResolveSecret returns the secret of the merchant terminal. It should return ErrUnknownMerchant if the
+	// merchant is not known.
+	ResolveSecret(ctx context.Context, merchantCode string, terminal int64) (string, error)
+}
+
+// SecretResolverFunc adapts a function to the SecretResolver interface.
+type SecretResolverFunc func(ctx context.Context, merchantCode string, terminal int64) (string, error)
+
+// ResolveSecret implements SecretResolver.
+func (fn SecretResolverFunc) ResolveSecret(ctx context.Context, merchantCode string, terminal int64) (string, error) {
+	return fn(ctx, merchantCode, terminal)
+}
+
+// ConfirmResolved works like Confirm for applications with multiple merchants sharing the same notification URL.
+// It reads the merchant code and terminal of the notification, finds the secret with the resolver and only then
+// verifies the signature. Notifications of unknown merchants return an error wrapping ErrUnknownMerchant.
+func ConfirmResolved(ctx context.Context, resolver SecretResolver, signed Signed) (Operation, error) {
+	// The params are not verified yet, they are only used to find out the secret.
+	params, err := ParseParams(signed)
+	if err != nil {
+		return Operation{}, fmt.Errorf("cannot parse params: %v", err)
+	}
+	secret, err := resolveSecret(ctx, resolver, params)
+	if err != nil {
+		return Operation{}, err
+	}
+	return Confirm(ctx, secret, signed)
+}
+
+func resolveSecret(ctx context.Context, resolver SecretResolver, params Params) (string, error) {
+	if params.MerchantCode == "" {
+		return "", fmt.Errorf("notification without merchant code: %w", ErrUnknownMerchant)
+	}
+	secret, err := resolver.ResolveSecret(ctx, params.MerchantCode, params.Terminal)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve secret of merchant %q terminal %d: %w", params.MerchantCode, params.Terminal, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("empty secret of merchant %q terminal %d: %w", params.MerchantCode, params.Terminal, ErrUnknownMerchant)
+	}
+	return secret, nil
+}
@@ -0,0 +1,97 @@
+package redsys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testResolver() SecretResolver {
+	return SecretResolverFunc(func(ctx context.Context, merchantCode string, terminal int64) (string, error) {
+		switch {
+		case merchantCode == "123456789" && terminal == 1:
+			return testSecret, nil
+		case merchantCode == "987654321" && terminal == 1:
+			return "QUJDREVGR0hJSktMTU5PUFFSU1RVVldY", nil
+		case merchantCode == "555555555":
+			return "", fmt.Errorf("database unavailable")
+		}
+		return "", ErrUnknownMerchant
+	})
+}
+
+func TestConfirmResolved(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_MerchantCode": "123456789", "Ds_Terminal": "001"})
+
+	operation, err := ConfirmResolved(context.Background(), testResolver(), signed)
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusApproved)
+	require.Equal(t, operation.Params.MerchantCode, "123456789")
+	require.EqualValues(t, operation.Params.Terminal, 1)
+}
+
+func TestConfirmResolvedUnknownMerchant(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_MerchantCode": "111111111", "Ds_Terminal": "001"})
+
+	_, err := ConfirmResolved(context.Background(), testResolver(), signed)
+	require.True(t, errors.Is(err, ErrUnknownMerchant))
+}
+
+func TestConfirmResolvedWithoutMerchant(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+
+	_, err := ConfirmResolved(context.Background(), testResolver(), signed)
+	require.True(t, errors.Is(err, ErrUnknownMerchant))
+}
+
+func TestConfirmResolvedEmptySecret(t *testing.T) {
+	resolver := SecretResolverFunc(func(ctx context.Context, merchantCode string, terminal int64) (string, error) {
+		return "", nil
+	})
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_MerchantCode": "123456789", "Ds_Terminal": "001"})
+
+	_, err := ConfirmResolved(context.Background(), resolver, signed)
+	require.True(t, errors.Is(err, ErrUnknownMerchant))
+}
+
+func TestConfirmResolvedOtherMerchantSecret(t *testing.T) {
+	// Signed with the secret of a different merchant than the one it claims to be.
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_MerchantCode": "987654321", "Ds_Terminal": "001"})
+
+	_, err := ConfirmResolved(context.Background(), testResolver(), signed)
+	require.ErrorContains(t, err, "bad signature")
+}
+
+func TestHandlerResolver(t *testing.T) {
+	var approved int
+	handler := &Handler{
+		Resolver: testResolver(),
+		OnApproved: func(ctx context.Context, operation Operation) error {
+			approved++
+			return nil
+		},
+	}
+
+	tests := []struct {
+		merchantCode string
+		status       int
+	}{
+		{"123456789", http.StatusOK},
+		{"111111111", http.StatusBadRequest},
+		{"987654321", http.StatusBadRequest},
+		{"555555555", http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_MerchantCode": test.merchantCode, "Ds_Terminal": "001"})
+		r := httptest.NewRequest(http.MethodGet, "/notification?"+notificationForm(signed).Encode(), nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		require.Equal(t, w.Code, test.status, test.merchantCode)
+	}
+	require.Equal(t, approved, 1)
+}
@@ -0,0 +1,371 @@
+package redsys
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResponseCategory groups the response codes of the bank by their meaning.
+type ResponseCategory string
+
+const (
+	// CategoryUnknown means the code is not in the catalog.
+	CategoryUnknown = ResponseCategory("")
+
+	// CategoryApproved means the operation was authorized.
+	CategoryApproved = ResponseCategory("approved")
+
+	// CategoryDenied means the issuer or the bank denied the operation.
+	CategoryDenied = ResponseCategory("denied")
+
+	// CategoryFraud means the operation was denied under suspicion of fraud.
+	CategoryFraud = ResponseCategory("fraud")
+
+	// CategoryAuthentication means the 3DS authentication of the cardholder failed.
+	CategoryAuthentication = ResponseCategory("authentication")
+
+	// CategoryCancelled means the user cancelled the operation or it was cancelled afterwards.
+	CategoryCancelled = ResponseCategory("cancelled")
+
+	// CategoryTechnical means the operation failed because of an error of the integration or the systems.
+	CategoryTechnical = ResponseCategory("technical")
+
+	// CategoryDuplicate means the order code was already used.
+	CategoryDuplicate = ResponseCategory("duplicate")
+
+	// CategoryPending means the operation has not finished yet.
+	CategoryPending = ResponseCategory("pending")
+)
+
+// Status returns the detailed status of the operations of the category:
+//
+//   - CategoryApproved: StatusApproved
+//   - CategoryDenied and CategoryAuthentication: StatusDenied
+//   - CategoryFraud: StatusFraud
+//   - CategoryCancelled: StatusCancelled
+//   - CategoryTechnical: StatusError
+//   - CategoryDuplicate: StatusRepeated
+//   - CategoryPending: StatusPending
+//   - CategoryUnknown: StatusUnknown
+func (category ResponseCategory) Status() Status {
+	switch category {
+	case CategoryApproved:
+		return StatusApproved
+	case CategoryDenied, CategoryAuthentication:
+		return StatusDenied
+	case CategoryFraud:
+		return StatusFraud
+	case CategoryCancelled:
+		return StatusCancelled
+	case CategoryTechnical:
+		return StatusError
+	case CategoryDuplicate:
+		return StatusRepeated
+	case CategoryPending:
+		return StatusPending
+	}
+	return StatusUnknown
+}
+
+// ResponseInfo describes a response code of the bank.
+type ResponseInfo struct {
+	// Code as sent by the bank, e.g. "0101" or "SIS0051".
+	Code string
+
+	// Description in Spanish.
+	DescriptionES string
+
+	// Description in English.
+	DescriptionEN string
+
+	// Category of the response.
+	Category ResponseCategory
+
+	// Retryable is true if repeating the payment, with a new order code, may succeed.
+	Retryable bool
+}
+
+type responseEntry struct {
+	category  ResponseCategory
+	retryable bool
+	es        string
+	en        string
+}
+
+func (entry responseEntry) info(code string) ResponseInfo {
+	return ResponseInfo{
+		Code:          code,
+		DescriptionES: entry.es,
+		DescriptionEN: entry.en,
+		Category:      entry.category,
+		Retryable:     entry.retryable,
+	}
+}
+
+// Ds_Response codes. The codes from 0000 to 0099 are all approved and they are not listed.
+var responses = map[int64]responseEntry{
+	400: {CategoryApproved, false, "Transacción autorizada para anulaciones", "Transaction authorized for cancellations"},
+	900: {CategoryApproved, false, "Transacción autorizada para devoluciones y confirmaciones", "Transaction authorized for refunds and confirmations"},
+
+	101: {CategoryDenied, false, "Tarjeta caducada", "Expired card"},
+	102: {CategoryFraud, false, "Tarjeta en excepción transitoria o bajo sospecha de fraude", "Card temporarily blocked or under suspicion of fraud"},
+	104: {CategoryDenied, false, "Operación no permitida para esa tarjeta o terminal", "Operation not allowed for the card or terminal"},
+	106: {CategoryDenied, false, "Intentos de PIN excedidos", "PIN attempts exceeded"},
+	116: {CategoryDenied, false, "Disponible insuficiente", "Insufficient funds"},
+	118: {CategoryDenied, false, "Tarjeta no registrada", "Card not registered"},
+	125: {CategoryDenied, false, "Tarjeta no efectiva", "Card not effective"},
+	129: {CategoryDenied, true, "Código de seguridad (CVV2/CVC2) incorrecto", "Incorrect security code (CVV2/CVC2)"},
+	167: {CategoryFraud, false, "Contactar con el emisor: sospecha de fraude", "Contact the issuer: suspicion of fraud"},
+	172: {CategoryDenied, false, "Denegada, no repetir", "Denied, do not retry"},
+	173: {CategoryDenied, false, "Denegada, no repetir sin actualizar los datos de la tarjeta", "Denied, do not retry without updating the card data"},
+	174: {CategoryDenied, true, "Denegada, no repetir antes de 72 horas", "Denied, do not retry within 72 hours"},
+	180: {CategoryDenied, false, "Tarjeta ajena al servicio", "Card not supported by the service"},
+	181: {CategoryDenied, false, "Tarjeta con restricciones de débito o crédito", "Card with debit or credit restrictions"},
+	182: {CategoryDenied, false, "Tarjeta con restricciones de débito o crédito", "Card with debit or credit restrictions"},
+	184: {CategoryAuthentication, true, "Error en la autenticación del titular", "Cardholder authentication error"},
+	190: {CategoryDenied, false, "Denegación del emisor sin especificar motivo", "Denied by the issuer without a reason"},
+	191: {CategoryDenied, true, "Fecha de caducidad errónea", "Wrong expiry date"},
+	195: {CategoryAuthentication, true, "Requiere autenticación SCA", "SCA authentication required"},
+	201: {CategoryDenied, false, "Tarjeta caducada", "Expired card"},
+	202: {CategoryFraud, false, "Tarjeta en excepción transitoria o bajo sospecha de fraude con retirada de tarjeta", "Card temporarily blocked or under suspicion of fraud, retain the card"},
+	204: {CategoryDenied, false, "Operación no permitida para esa tarjeta o terminal", "Operation not allowed for the card or terminal"},
+	207: {CategoryFraud, false, "Retener tarjeta, contactar con el emisor", "Retain the card, contact the issuer"},
+	208: {CategoryFraud, false, "Tarjeta perdida o robada", "Lost or stolen card"},
+	209: {CategoryFraud, false, "Tarjeta perdida o robada", "Lost or stolen card"},
+	280: {CategoryDenied, true, "Error en el código de seguridad (CVV2/CVC2)", "Security code (CVV2/CVC2) error"},
+	290: {CategoryDenied, false, "Denegación del emisor sin especificar motivo", "Denied by the issuer without a reason"},
+	904: {CategoryTechnical, false, "Comercio no registrado en el FUC", "Merchant not registered"},
+	909: {CategoryTechnical, true, "Error de sistema", "System error"},
+	912: {CategoryTechnical, true, "Emisor no disponible", "Issuer not available"},
+	913: {CategoryDuplicate, false, "Pedido repetido", "Duplicate order"},
+	940: {CategoryTechnical, false, "Transacción anulada anteriormente", "Transaction previously cancelled"},
+	941: {CategoryTechnical, false, "Transacción de autorización ya anulada por una anulación anterior", "Authorization already cancelled by a previous cancellation"},
+	942: {CategoryTechnical, false, "Transacción de autorización original denegada", "Original authorization denied"},
+	943: {CategoryTechnical, false, "Datos de la transacción original distintos", "Different data of the original transaction"},
+	944: {CategoryTechnical, true, "Sesión incorrecta", "Wrong session"},
+	945: {CategoryDuplicate, false, "Transmisión doble de la misma transacción", "Double transmission of the same transaction"},
+	946: {CategoryTechnical, true, "Operación a anular en proceso", "Operation to cancel in progress"},
+	947: {CategoryDuplicate, false, "Transmisión doble en proceso", "Double transmission in progress"},
+	949: {CategoryTechnical, true, "Terminal inoperativo", "Terminal out of service"},
+	950: {CategoryDenied, false, "Operación de devolución no permitida", "Refund not allowed"},
+	965: {CategoryDenied, false, "Violación de la normativa de Visa o Mastercard", "Violation of the Visa or Mastercard rules"},
+
+	9912: {CategoryTechnical, true, "Emisor no disponible", "Issuer not available"},
+	9913: {CategoryTechnical, false, "Error en la confirmación que el comercio envía al TPV Virtual", "Error in the confirmation sent by the merchant to the virtual POS"},
+	9914: {CategoryTechnical, false, "Confirmación KO del comercio", "KO confirmation of the merchant"},
+	9915: {CategoryCancelled, true, "A petición del usuario se ha cancelado el pago", "Payment cancelled by the user"},
+	9928: {CategoryCancelled, false, "Anulación de autorización en diferido realizada por el SIS", "Deferred authorization cancelled by the SIS"},
+	9929: {CategoryCancelled, false, "Anulación de autorización en diferido realizada por el comercio", "Deferred authorization cancelled by the merchant"},
+	9997: {CategoryTechnical, true, "Se está procesando otra transacción con la misma tarjeta", "Another transaction with the same card is being processed"},
+	9998: {CategoryPending, false, "Operación en proceso de solicitud de datos de tarjeta", "Operation waiting for the card data"},
+	9999: {CategoryPending, false, "Operación redirigida al emisor para autenticar", "Operation redirected to the issuer for authentication"},
+}
+
+// SIS error codes. The Ds_Response codes from 9000 to 9899 are the same errors as SIS0000 to SIS0899. Codes not
+// listed return false in the lookups.
+var sisErrors = map[string]responseEntry{
+	"SIS0007": {CategoryTechnical, false, "Error al desmontar el XML de entrada", "Error parsing the input XML"},
+	"SIS0008": {CategoryTechnical, false, "Falta el campo Ds_Merchant_MerchantCode", "Missing Ds_Merchant_MerchantCode"},
+	"SIS0009": {CategoryTechnical, false, "Error de formato en Ds_Merchant_MerchantCode", "Invalid format of Ds_Merchant_MerchantCode"},
+	"SIS0010": {CategoryTechnical, false, "Falta el campo Ds_Merchant_Terminal", "Missing Ds_Merchant_Terminal"},
+	"SIS0011": {CategoryTechnical, false, "Error de formato en Ds_Merchant_Terminal", "Invalid format of Ds_Merchant_Terminal"},
+	"SIS0014": {CategoryTechnical, false, "Error de formato en Ds_Merchant_Order", "Invalid format of Ds_Merchant_Order"},
+	"SIS0015": {CategoryTechnical, false, "Falta el campo Ds_Merchant_Currency", "Missing Ds_Merchant_Currency"},
+	"SIS0016": {CategoryTechnical, false, "Error de formato en Ds_Merchant_Currency", "Invalid format of Ds_Merchant_Currency"},
+	"SIS0018": {CategoryTechnical, false, "Falta el campo Ds_Merchant_Amount", "Missing Ds_Merchant_Amount"},
+	"SIS0019": {CategoryTechnical, false, "Error de formato en Ds_Merchant_Amount", "Invalid format of Ds_Merchant_Amount"},
+	"SIS0020": {CategoryTechnical, false, "Falta el campo Ds_Merchant_MerchantSignature", "Missing Ds_Merchant_MerchantSignature"},
+	"SIS0021": {CategoryTechnical, false, "El campo Ds_Merchant_MerchantSignature viene vacío", "Empty Ds_Merchant_MerchantSignature"},
+	"SIS0022": {CategoryTechnical, false, "Error de formato en Ds_Merchant_TransactionType", "Invalid format of Ds_Merchant_TransactionType"},
+	"SIS0023": {CategoryTechnical, false, "Ds_Merchant_TransactionType desconocido", "Unknown Ds_Merchant_TransactionType"},
+	"SIS0024": {CategoryTechnical, false, "Ds_Merchant_ConsumerLanguage tiene más de 3 posiciones", "Ds_Merchant_ConsumerLanguage has more than 3 digits"},
+	"SIS0025": {CategoryTechnical, false, "Error de formato en Ds_Merchant_ConsumerLanguage", "Invalid format of Ds_Merchant_ConsumerLanguage"},
+	"SIS0026": {CategoryTechnical, false, "No existe el comercio / terminal enviado", "The merchant / terminal does not exist"},
+	"SIS0027": {CategoryTechnical, false, "La moneda enviada por el comercio es diferente a la que tiene asignada", "The currency is different from the one assigned to the merchant"},
+	"SIS0028": {CategoryTechnical, false, "El comercio / terminal está dado de baja", "The merchant / terminal is disabled"},
+	"SIS0030": {CategoryTechnical, false, "Tipo de operación no válido para un pago con tarjeta", "Invalid transaction type for a card payment"},
+	"SIS0031": {CategoryTechnical, false, "Método de pago no definido", "Payment method not defined"},
+	"SIS0033": {CategoryTechnical, false, "Tipo de operación no válido para un pago con móvil", "Invalid transaction type for a mobile payment"},
+	"SIS0034": {CategoryTechnical, true, "Error de acceso a la base de datos", "Database access error"},
+	"SIS0037": {CategoryDenied, true, "El número de teléfono no es válido", "Invalid phone number"},
+	"SIS0038": {CategoryTechnical, true, "Error en java", "Java error"},
+	"SIS0040": {CategoryTechnical, false, "El comercio / terminal no tiene ningún método de pago asignado", "The merchant / terminal does not have any payment method assigned"},
+	"SIS0041": {CategoryTechnical, false, "Error en el cálculo de la firma de datos del comercio", "Error calculating the signature of the merchant data"},
+	"SIS0042": {CategoryTechnical, false, "La firma enviada no es correcta", "Wrong signature"},
+	"SIS0043": {CategoryTechnical, true, "Error al realizar la notificación on-line", "Error sending the online notification"},
+	"SIS0046": {CategoryDenied, false, "El BIN de la tarjeta no está dado de alta", "The BIN of the card is not registered"},
+	"SIS0051": {CategoryDuplicate, false, "Número de pedido repetido", "Duplicate order number"},
+	"SIS0054": {CategoryTechnical, false, "No existe operación sobre la que realizar la devolución", "There is no operation to refund"},
+	"SIS0055": {CategoryTechnical, false, "Existe más de un pago con el mismo número de pedido", "There is more than one payment with the same order number"},
+	"SIS0056": {CategoryTechnical, false, "La operación sobre la que se desea devolver no está autorizada", "The operation to refund is not authorized"},
+	"SIS0057": {CategoryTechnical, false, "El importe a devolver supera el permitido", "The amount to refund exceeds the allowed one"},
+	"SIS0058": {CategoryTechnical, false, "Inconsistencia de datos en la validación de una confirmación", "Inconsistent data validating a confirmation"},
+	"SIS0059": {CategoryTechnical, false, "No existe operación sobre la que realizar la confirmación", "There is no operation to confirm"},
+	"SIS0060": {CategoryTechnical, false, "Ya existe una confirmación asociada a la preautorización", "The pre-authorization is already confirmed"},
+	"SIS0061": {CategoryTechnical, false, "La preautorización sobre la que se desea confirmar no está autorizada", "The pre-authorization to confirm is not authorized"},
+	"SIS0062": {CategoryTechnical, false, "El importe a confirmar supera el permitido", "The amount to confirm exceeds the allowed one"},
+	"SIS0063": {CategoryTechnical, false, "Número de tarjeta no disponible", "Card number not available"},
+	"SIS0064": {CategoryDenied, true, "Número de posiciones de la tarjeta incorrecto", "Wrong number of digits of the card"},
+	"SIS0065": {CategoryDenied, true, "El número de tarjeta no es numérico", "The card number is not numeric"},
+	"SIS0066": {CategoryDenied, true, "Mes de caducidad no disponible", "Expiry month not available"},
+	"SIS0067": {CategoryDenied, true, "El mes de caducidad no es numérico", "The expiry month is not numeric"},
+	"SIS0068": {CategoryDenied, true, "El mes de caducidad no es válido", "Invalid expiry month"},
+	"SIS0069": {CategoryDenied, true, "El año de caducidad no es numérico", "The expiry year is not numeric"},
+	"SIS0070": {CategoryDenied, true, "El año de caducidad no es válido", "Invalid expiry year"},
+	"SIS0071": {CategoryDenied, false, "Tarjeta caducada", "Expired card"},
+	"SIS0072": {CategoryTechnical, false, "Operación no anulable", "The operation cannot be cancelled"},
+	"SIS0074": {CategoryTechnical, false, "Falta el campo Ds_Merchant_Order", "Missing Ds_Merchant_Order"},
+	"SIS0075": {CategoryTechnical, false, "Ds_Merchant_Order tiene menos de 4 posiciones o más de 12", "Ds_Merchant_Order has less than 4 or more than 12 characters"},
+	"SIS0076": {CategoryTechnical, false, "Ds_Merchant_Order no tiene las cuatro primeras posiciones numéricas", "The first four characters of Ds_Merchant_Order are not numeric"},
+	"SIS0078": {CategoryDenied, false, "Método de pago no disponible", "Payment method not available"},
+	"SIS0079": {CategoryTechnical, true, "Error al realizar el pago con tarjeta", "Error processing the card payment"},
+	"SIS0080": {CategoryTechnical, true, "Error genérico", "Generic error"},
+	"SIS0081": {CategoryTechnical, true, "La sesión es nueva, se han perdido los datos almacenados", "New session, the stored data was lost"},
+	"SIS0084": {CategoryTechnical, false, "El valor de Ds_Merchant_Conciliation es nulo", "Ds_Merchant_Conciliation is null"},
+	"SIS0085": {CategoryTechnical, false, "El valor de Ds_Merchant_Conciliation no es numérico", "Ds_Merchant_Conciliation is not numeric"},
+	"SIS0086": {CategoryTechnical, false, "El valor de Ds_Merchant_Conciliation no ocupa 6 posiciones", "Ds_Merchant_Conciliation does not have 6 digits"},
+	"SIS0089": {CategoryTechnical, false, "Ds_Merchant_ExpiryDate no ocupa 4 posiciones", "Ds_Merchant_ExpiryDate does not have 4 digits"},
+	"SIS0092": {CategoryTechnical, false, "Ds_Merchant_ExpiryDate tiene un valor nulo", "Ds_Merchant_ExpiryDate is null"},
+	"SIS0093": {CategoryDenied, false, "Tarjeta no encontrada en la tabla de rangos", "Card not found in the ranges table"},
+	"SIS0094": {CategoryDenied, false, "Tarjeta rechazada por los sistemas internacionales", "Card rejected by the international systems"},
+	"SIS0097": {CategoryTechnical, false, "Valor del campo Ds_Merchant_CComercio no válido", "Invalid Ds_Merchant_CComercio"},
+	"SIS0098": {CategoryTechnical, false, "Valor del campo Ds_Merchant_CVentana no válido", "Invalid Ds_Merchant_CVentana"},
+	"SIS0104": {CategoryAuthentication, false, "Comercio con titular seguro y titular sin clave de compra segura", "Secure merchant and cardholder without secure purchase key"},
+	"SIS0112": {CategoryTechnical, false, "El tipo de transacción de Ds_Merchant_TransactionType no está permitido", "The transaction type of Ds_Merchant_TransactionType is not allowed"},
+	"SIS0113": {CategoryTechnical, true, "Excepción producida en el servlet de operaciones", "Exception in the operations servlet"},
+	"SIS0114": {CategoryTechnical, false, "Se ha llamado con un GET en lugar de un POST", "Called with GET instead of POST"},
+	"SIS0115": {CategoryTechnical, false, "No existe operación sobre la que realizar el pago de la cuota", "There is no operation to pay the installment"},
+	"SIS0116": {CategoryTechnical, false, "La operación sobre la que se desea pagar una cuota no es válida", "The operation to pay the installment is not valid"},
+	"SIS0117": {CategoryTechnical, false, "La operación sobre la que se desea pagar una cuota no está autorizada", "The operation to pay the installment is not authorized"},
+	"SIS0118": {CategoryTechnical, false, "Se ha excedido el importe total de las cuotas", "The total amount of the installments was exceeded"},
+	"SIS0119": {CategoryTechnical, false, "Valor del campo Ds_Merchant_DateFrecuency no válido", "Invalid Ds_Merchant_DateFrecuency"},
+	"SIS0120": {CategoryTechnical, false, "Valor del campo Ds_Merchant_ChargeExpiryDate no válido", "Invalid Ds_Merchant_ChargeExpiryDate"},
+	"SIS0121": {CategoryTechnical, false, "Valor del campo Ds_Merchant_SumTotal no válido", "Invalid Ds_Merchant_SumTotal"},
+	"SIS0122": {CategoryTechnical, false, "Formato incorrecto de Ds_Merchant_DateFrecuency o Ds_Merchant_SumTotal", "Invalid format of Ds_Merchant_DateFrecuency or Ds_Merchant_SumTotal"},
+	"SIS0123": {CategoryTechnical, false, "Se ha excedido la fecha tope para realizar transacciones", "The deadline to make transactions was exceeded"},
+	"SIS0124": {CategoryTechnical, false, "No ha transcurrido la frecuencia mínima en un pago recurrente sucesivo", "The minimum frequency of a subsequent recurring payment has not passed"},
+	"SIS0132": {CategoryTechnical, false, "La confirmación no puede superar en más de 7 días a la preautorización", "The confirmation cannot be more than 7 days after the pre-authorization"},
+	"SIS0133": {CategoryTechnical, false, "La confirmación no puede superar en más de 45 días a la autenticación previa", "The confirmation cannot be more than 45 days after the authentication"},
+	"SIS0139": {CategoryDuplicate, false, "El pago recurrente inicial está duplicado", "Duplicate initial recurring payment"},
+	"SIS0142": {CategoryCancelled, true, "Tiempo excedido para el pago", "Payment time exceeded"},
+	"SIS0197": {CategoryTechnical, true, "Error al obtener los datos de la cesta de la compra", "Error getting the shopping cart data"},
+	"SIS0198": {CategoryDenied, false, "El importe supera el límite permitido para el comercio", "The amount exceeds the merchant limit"},
+	"SIS0199": {CategoryDenied, false, "El número de operaciones supera el límite permitido para el comercio", "The number of operations exceeds the merchant limit"},
+	"SIS0200": {CategoryDenied, false, "El importe acumulado supera el límite permitido para el comercio", "The accumulated amount exceeds the merchant limit"},
+	"SIS0214": {CategoryTechnical, false, "El comercio no admite devoluciones", "The merchant does not allow refunds"},
+	"SIS0216": {CategoryDenied, true, "El CVV2 tiene más de 3 posiciones", "The CVV2 has more than 3 digits"},
+	"SIS0217": {CategoryDenied, true, "Error de formato en el CVV2", "Invalid format of the CVV2"},
+	"SIS0218": {CategoryTechnical, false, "El comercio no permite operaciones seguras por esta entrada", "The merchant does not allow secure operations through this entry"},
+	"SIS0219": {CategoryFraud, false, "El número de operaciones de la tarjeta supera el límite permitido para el comercio", "The number of operations of the card exceeds the merchant limit"},
+	"SIS0220": {CategoryFraud, false, "El importe acumulado de la tarjeta supera el límite permitido para el comercio", "The accumulated amount of the card exceeds the merchant limit"},
+	"SIS0221": {CategoryDenied, true, "El CVV2 es obligatorio", "The CVV2 is required"},
+	"SIS0222": {CategoryTechnical, false, "Ya existe una anulación asociada a la preautorización", "The pre-authorization is already cancelled"},
+	"SIS0223": {CategoryTechnical, false, "La preautorización que se desea anular no está autorizada", "The pre-authorization to cancel is not authorized"},
+	"SIS0224": {CategoryTechnical, false, "El comercio no permite anulaciones por no tener firma ampliada", "The merchant does not allow cancellations without the extended signature"},
+	"SIS0225": {CategoryTechnical, false, "No existe operación sobre la que realizar la anulación", "There is no operation to cancel"},
+	"SIS0226": {CategoryTechnical, false, "Inconsistencia de datos en la validación de una anulación", "Inconsistent data validating a cancellation"},
+	"SIS0227": {CategoryTechnical, false, "Valor del campo Ds_Merchant_TransactionDate no válido", "Invalid Ds_Merchant_TransactionDate"},
+	"SIS0229": {CategoryTechnical, false, "No existe el código de pago aplazado solicitado", "The deferred payment code does not exist"},
+	"SIS0252": {CategoryTechnical, false, "El comercio no permite el envío de tarjeta", "The merchant does not allow sending the card"},
+	"SIS0253": {CategoryDenied, true, "La tarjeta no cumple el check-digit", "The card does not pass the check digit"},
+	"SIS0254": {CategoryFraud, false, "El número de operaciones de la IP supera el límite permitido por el comercio", "The number of operations of the IP exceeds the merchant limit"},
+	"SIS0255": {CategoryFraud, false, "El importe acumulado por la IP supera el límite permitido por el comercio", "The accumulated amount of the IP exceeds the merchant limit"},
+	"SIS0256": {CategoryTechnical, false, "El comercio no puede realizar preautorizaciones", "The merchant cannot make pre-authorizations"},
+	"SIS0257": {CategoryDenied, false, "La tarjeta no permite preautorizaciones", "The card does not allow pre-authorizations"},
+	"SIS0258": {CategoryTechnical, false, "Inconsistencia de datos en la validación de una confirmación", "Inconsistent data validating a confirmation"},
+	"SIS0261": {CategoryFraud, false, "Operación detenida por superar el control de restricciones en la entrada al SIS", "Operation stopped by the restrictions control of the SIS"},
+	"SIS0270": {CategoryTechnical, false, "El comercio no puede realizar autorizaciones en diferido", "The merchant cannot make deferred authorizations"},
+	"SIS0274": {CategoryTechnical, false, "Tipo de operación desconocida o no permitida por esta entrada al SIS", "Unknown transaction type or not allowed through this entry"},
+	"SIS0298": {CategoryTechnical, false, "El comercio no permite realizar operaciones de tarjeta en archivo", "The merchant does not allow card on file operations"},
+	"SIS0319": {CategoryTechnical, false, "El comercio no pertenece al grupo especificado en Ds_Merchant_Group", "The merchant does not belong to the group of Ds_Merchant_Group"},
+	"SIS0321": {CategoryDenied, false, "El identificador de Ds_Merchant_Identifier no está asociado al comercio", "The Ds_Merchant_Identifier token does not belong to the merchant"},
+	"SIS0322": {CategoryTechnical, false, "Error de formato en Ds_Merchant_Group", "Invalid format of Ds_Merchant_Group"},
+	"SIS0325": {CategoryTechnical, false, "Se ha pedido no mostrar pantallas pero no se ha enviado ninguna referencia de tarjeta", "Asked to not show screens without sending a card reference"},
+	"SIS0429": {CategoryTechnical, false, "Error en la versión de Ds_SignatureVersion", "Invalid Ds_SignatureVersion"},
+	"SIS0430": {CategoryTechnical, false, "Error al decodificar el parámetro Ds_MerchantParameters", "Error decoding Ds_MerchantParameters"},
+	"SIS0431": {CategoryTechnical, false, "Error del objeto JSON enviado en Ds_MerchantParameters", "Invalid JSON object in Ds_MerchantParameters"},
+	"SIS0432": {CategoryTechnical, false, "FUC del comercio erróneo", "Wrong merchant code"},
+	"SIS0433": {CategoryTechnical, false, "Terminal del comercio erróneo", "Wrong merchant terminal"},
+	"SIS0434": {CategoryTechnical, false, "Falta el número de pedido de la operación", "Missing order number"},
+	"SIS0435": {CategoryTechnical, false, "Error en el cálculo de la firma", "Error calculating the signature"},
+	"SIS0436": {CategoryTechnical, false, "Error en la construcción del elemento padre REQUEST", "Error building the REQUEST element"},
+	"SIS0437": {CategoryTechnical, false, "Error en la construcción del elemento DS_SIGNATUREVERSION", "Error building the DS_SIGNATUREVERSION element"},
+	"SIS0438": {CategoryTechnical, false, "Error en la construcción del elemento DATOSENTRADA", "Error building the DATOSENTRADA element"},
+	"SIS0439": {CategoryTechnical, false, "Error en la construcción del elemento DS_SIGNATURE", "Error building the DS_SIGNATURE element"},
+	"SIS0444": {CategoryTechnical, false, "Se está usando una firma antigua y el comercio está configurado como HMAC SHA256", "Old signature used with a merchant configured as HMAC SHA256"},
+	"SIS0448": {CategoryDenied, false, "La tarjeta es DINERS y el comercio no tiene el método de pago Pago DINERS", "DINERS card and the merchant does not accept DINERS payments"},
+	"SIS0453": {CategoryDenied, false, "La tarjeta es JCB y el comercio no tiene el método de pago Pago JCB", "JCB card and the merchant does not accept JCB payments"},
+	"SIS0454": {CategoryDenied, false, "La tarjeta es AMEX y el comercio no tiene el método de pago Pago Amex", "AMEX card and the merchant does not accept AMEX payments"},
+	"SIS0463": {CategoryDenied, false, "La tarjeta no es nacional y el comercio no tiene el método de pago Tarjetas No Nacionales", "Foreign card and the merchant does not accept foreign cards"},
+	"SIS0589": {CategoryAuthentication, true, "Operación de autenticación EMV3DS rechazada, respuesta sin CRes", "EMV3DS authentication rejected, response without CRes"},
+	"SIS0590": {CategoryAuthentication, true, "Operación de autenticación EMV3DS rechazada, error al desmontar la respuesta CRes", "EMV3DS authentication rejected, error parsing the CRes"},
+	"SIS0593": {CategoryAuthentication, true, "Error en la autenticación EMV3DS, el transStatus de la consulta final no está definido", "EMV3DS authentication error, undefined transStatus in the final query"},
+	"SIS0599": {CategoryAuthentication, true, "Error en la operación de autenticación EMV3DS", "EMV3DS authentication error"},
+	"SIS0600": {CategoryAuthentication, false, "El emisor indica que no es posible autenticar la tarjeta (ARes N)", "The issuer cannot authenticate the card (ARes N)"},
+	"SIS0601": {CategoryAuthentication, false, "El emisor indica que no es posible autenticar la tarjeta (ARes R)", "The issuer cannot authenticate the card (ARes R)"},
+	"SIS0602": {CategoryAuthentication, true, "Error en el proceso de autenticación 3DSecure v2 (ARes U)", "3DSecure v2 authentication error (ARes U)"},
+	"SIS0673": {CategoryCancelled, true, "Operación cancelada, el usuario no desea seguir", "Operation cancelled, the user does not want to continue"},
+	"SIS0754": {CategoryAuthentication, false, "La tarjeta no permite autenticación en versión 2", "The card does not allow authentication version 2"},
+}
+
+// LookupResponse returns the description of a Ds_Response code of the bank.
+func LookupResponse(code int64) (ResponseInfo, bool) {
+	formatted := fmt.Sprintf("%04d", code)
+	if code >= 0 && code <= 99 {
+		return ResponseInfo{
+			Code:          formatted,
+			DescriptionES: "Transacción autorizada para pagos y preautorizaciones",
+			DescriptionEN: "Transaction authorized for payments and pre-authorizations",
+			Category:      CategoryApproved,
+		}, true
+	}
+	if entry, ok := responses[code]; ok {
+		return entry.info(formatted), true
+	}
+	if code >= 9000 && code <= 9899 {
+		if entry, ok := sisErrors[fmt.Sprintf("SIS0%03d", code-9000)]; ok {
+			return entry.info(formatted), true
+		}
+	}
+	return ResponseInfo{}, false
+}
+
+// LookupSISError returns the description of a SIS error code of the bank like "SIS0051".
+func LookupSISError(code string) (ResponseInfo, bool) {
+	code = strings.ToUpper(code)
+	if entry, ok := sisErrors[code]; ok {
+		return entry.info(code), true
+	}
+	return ResponseInfo{}, false
+}
+
+// Response returns the description of the response code of the operation. Codes outside of the catalog return
+// CategoryUnknown.
+func (operation Operation) Response() ResponseInfo {
+	if operation.Params.RawResponse == "" {
+		return ResponseInfo{}
+	}
+	info, ok := lookupResponse(operation.ResponseCode, operation.Params.IsBizum())
+	if !ok {
+		return ResponseInfo{Code: fmt.Sprintf("%04d", operation.ResponseCode)}
+	}
+	return info
+}
+
+// lookupResponse returns the description of the response code with the Bizum catalog if the bank processed the
+// operation with Bizum.
+func lookupResponse(code int64, bizum bool) (ResponseInfo, bool) {
+	if bizum {
+		return LookupBizumResponse(code)
+	}
+	return LookupResponse(code)
+}
+
+// Info returns the description of the error. Codes outside of the catalog return CategoryUnknown.
+func (err *SISError) Info() ResponseInfo {
+	info, ok := LookupSISError(err.Code)
+	if !ok {
+		return ResponseInfo{Code: err.Code}
+	}
+	return info
+}
@@ -0,0 +1,102 @@
+package redsys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupResponse(t *testing.T) {
+	tests := []struct {
+		code      int64
+		formatted string
+		category  ResponseCategory
+		retryable bool
+	}{
+		{0, "0000", CategoryApproved, false},
+		{99, "0099", CategoryApproved, false},
+		{900, "0900", CategoryApproved, false},
+		{116, "0116", CategoryDenied, false},
+		{129, "0129", CategoryDenied, true},
+		{208, "0208", CategoryFraud, false},
+		{184, "0184", CategoryAuthentication, true},
+		{913, "0913", CategoryDuplicate, false},
+		{909, "0909", CategoryTechnical, true},
+		{9915, "9915", CategoryCancelled, true},
+		{9051, "9051", CategoryDuplicate, false},
+		{9602, "9602", CategoryAuthentication, true},
+		{9999, "9999", CategoryPending, false},
+	}
+	for _, test := range tests {
+		info, ok := LookupResponse(test.code)
+		require.True(t, ok, test.code)
+		require.Equal(t, info.Code, test.formatted)
+		require.Equal(t, info.Category, test.category, test.code)
+		require.Equal(t, info.Retryable, test.retryable, test.code)
+		require.NotEmpty(t, info.DescriptionES)
+		require.NotEmpty(t, info.DescriptionEN)
+	}
+}
+
+func TestLookupResponseReferencedCodes(t *testing.T) {
+	var codes []int64
+	codes = append(codes, 400, 900, 913, 9051)
+	codes = append(codes, legacyCancelledCodes...)
+	codes = append(codes, expiredCardCodes...)
+	codes = append(codes, insufficientFundsCodes...)
+	codes = append(codes, wrongCardDataCodes...)
+	for _, code := range codes {
+		_, ok := LookupResponse(code)
+		require.True(t, ok, code)
+	}
+}
+
+func TestLookupResponseSIS(t *testing.T) {
+	info, ok := LookupResponse(9066)
+	require.True(t, ok)
+	require.Equal(t, info.Code, "9066")
+	require.Equal(t, info.Category, CategoryDenied)
+	require.Equal(t, info.DescriptionEN, "Expiry month not available")
+}
+
+func TestLookupResponseUnknown(t *testing.T) {
+	_, ok := LookupResponse(555)
+	require.False(t, ok)
+
+	_, ok = LookupResponse(9001)
+	require.False(t, ok)
+}
+
+func TestLookupSISError(t *testing.T) {
+	info, ok := LookupSISError("SIS0051")
+	require.True(t, ok)
+	require.Equal(t, info.Code, "SIS0051")
+	require.Equal(t, info.Category, CategoryDuplicate)
+	require.Equal(t, info.DescriptionEN, "Duplicate order number")
+
+	_, ok = LookupSISError("XML0010")
+	require.False(t, ok)
+}
+
+func TestOperationResponse(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0190"})
+	operation, err := Confirm(context.Background(), testSecret, signed)
+	require.NoError(t, err)
+
+	info := operation.Response()
+	require.Equal(t, info.Code, "0190")
+	require.Equal(t, info.Category, CategoryDenied)
+	require.Equal(t, info.DescriptionES, "Denegación del emisor sin especificar motivo")
+
+	require.Equal(t, Operation{}.Response(), ResponseInfo{})
+	require.Equal(t, Operation{Params: Params{RawResponse: "0555"}, ResponseCode: 555}.Response(), ResponseInfo{Code: "0555"})
+}
+
+func TestSISErrorInfo(t *testing.T) {
+	err := &SISError{Code: "SIS0042"}
+	require.Equal(t, err.Info().Category, CategoryTechnical)
+
+	err = &SISError{Code: "SIS9999"}
+	require.Equal(t, err.Info(), ResponseInfo{Code: "SIS9999"})
+}
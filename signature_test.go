@@ -177,7 +177,7 @@ func TestConfirmBadSignature(t *testing.T) {
 		Params:           base64.StdEncoding.EncodeToString([]byte(params)),
 	}
 	_, err := Confirm(context.Background(), "sq7HjrUOBfKmC576ILgskD5srU870gJ7", signed)
-	require.EqualError(t, err, `bad signature, got "foobarqu" expected "cQ3etTziVy1Dzvs72w9KS8vDALtU0EPiDm0rWvb7mBU="`)
+	require.EqualError(t, err, `bad signature "foobarqu"`)
 }
 
 func TestConfirmCancellations(t *testing.T) {
@@ -238,3 +238,152 @@ func TestConfirmNonUTF78Transaction(t *testing.T) {
 
 	require.EqualValues(t, params.Response, 9601)
 }
+
+func TestSignCurrency(t *testing.T) {
+	merchant := Merchant{
+		Secret:   "sq7HjrUOBfKmC576ILgskD5srU870gJ7",
+		Currency: CurrencyPounds,
+	}
+	session := Session{
+		Order: "00011234abcd",
+	}
+	signed, err := Sign(context.Background(), merchant, session)
+	require.NoError(t, err)
+
+	decoded, err := base64.StdEncoding.DecodeString(signed.Params)
+	require.NoError(t, err)
+	params := map[string]interface{}{}
+	err = json.Unmarshal(decoded, &params)
+	require.NoError(t, err)
+
+	require.Equal(t, params["Ds_Merchant_Currency"], float64(826))
+}
+
+func TestSignUnsupportedCurrency(t *testing.T) {
+	merchant := Merchant{
+		Secret:   "sq7HjrUOBfKmC576ILgskD5srU870gJ7",
+		Currency: Currency(999),
+	}
+	session := Session{
+		Order: "00011234abcd",
+	}
+	_, err := Sign(context.Background(), merchant, session)
+	require.EqualError(t, err, "unknown currency 999")
+}
+
+func TestParseParamsReadsCurrency(t *testing.T) {
+	paramsEncoded := `{"Ds_Order": "order-code", "Ds_Currency": "840"}`
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           base64.StdEncoding.EncodeToString([]byte(paramsEncoded)),
+	}
+	params, err := ParseParams(signed)
+	require.NoError(t, err)
+
+	require.Equal(t, params.Currency, CurrencyDollars)
+}
+
+func TestParseParamsReadsAmountAndTransactionType(t *testing.T) {
+	paramsEncoded := `{"Ds_Order": "order-code", "Ds_Amount": "26588", "Ds_TransactionType": "3"}`
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           base64.StdEncoding.EncodeToString([]byte(paramsEncoded)),
+	}
+	params, err := ParseParams(signed)
+	require.NoError(t, err)
+
+	require.EqualValues(t, params.Amount, 26588)
+	require.Equal(t, params.TransactionType, TransactionTypeRefund)
+}
+
+func TestSignRequestsToken(t *testing.T) {
+	merchant := Merchant{
+		Secret: "sq7HjrUOBfKmC576ILgskD5srU870gJ7",
+	}
+	session := Session{
+		Order:      "00011234abcd",
+		Identifier: IdentifierRequired,
+	}
+	signed, err := Sign(context.Background(), merchant, session)
+	require.NoError(t, err)
+
+	decoded, err := base64.StdEncoding.DecodeString(signed.Params)
+	require.NoError(t, err)
+	params := map[string]interface{}{}
+	err = json.Unmarshal(decoded, &params)
+	require.NoError(t, err)
+
+	require.Equal(t, params["Ds_Merchant_Identifier"], "REQUIRED")
+}
+
+func TestParseParamsReadsToken(t *testing.T) {
+	paramsEncoded := `{"Ds_Order": "order-code", "Ds_Merchant_Identifier": "b4ef9bfc3bd8e2a0bd2ad7aeb3c1b0d8b2a7e7d1", "Ds_ExpiryDate": "2812"}`
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Params:           base64.StdEncoding.EncodeToString([]byte(paramsEncoded)),
+	}
+	params, err := ParseParams(signed)
+	require.NoError(t, err)
+
+	require.Equal(t, params.Identifier, "b4ef9bfc3bd8e2a0bd2ad7aeb3c1b0d8b2a7e7d1")
+	require.Equal(t, params.ExpiryDate, "2812")
+}
+
+func TestConfirmShortOrder(t *testing.T) {
+	params := `{"Ds_Order": "123"}`
+	signed := Signed{
+		SignatureVersion: "HMAC_SHA256_V1",
+		Signature:        "foobarqu",
+		Params:           base64.StdEncoding.EncodeToString([]byte(params)),
+	}
+	_, err := Confirm(context.Background(), "sq7HjrUOBfKmC576ILgskD5srU870gJ7", signed)
+	require.ErrorContains(t, err, "bad signature")
+}
+
+func TestConfirmOutcome(t *testing.T) {
+	tests := []struct {
+		response string
+		status   Status
+		outcome  Status
+	}{
+		{"0000", StatusApproved, StatusApproved},
+		{"0900", StatusApproved, StatusApproved},
+		{"9915", StatusCancelled, StatusCancelled},
+		{"0190", StatusCancelled, StatusDenied},
+		{"0116", StatusUnknown, StatusDenied},
+		{"0208", StatusUnknown, StatusFraud},
+		{"9080", StatusCancelled, StatusError},
+		{"0913", StatusRepeated, StatusRepeated},
+		{"9999", StatusUnknown, StatusPending},
+		{"0555", StatusUnknown, StatusUnknown},
+	}
+	for _, test := range tests {
+		signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": test.response})
+		operation, err := Confirm(context.Background(), testSecret, signed)
+		require.NoError(t, err)
+		require.Equal(t, operation.Status, test.status, test.response)
+		require.Equal(t, operation.Outcome, test.outcome, test.response)
+	}
+}
+
+func TestPadKey(t *testing.T) {
+	require.Equal(t, padKey("00011234abcd"), []byte("00011234abcd\x00\x00\x00\x00"))
+	require.Equal(t, padKey("123456789"), []byte("123456789\x00\x00\x00\x00\x00\x00\x00"))
+	require.Equal(t, padKey("12345678"), []byte("12345678"))
+	require.Equal(t, padKey("123"), []byte("123\x00\x00\x00\x00\x00"))
+	require.Equal(t, padKey(""), []byte("\x00\x00\x00\x00\x00\x00\x00\x00"))
+}
+
+func TestSignKeyLengths(t *testing.T) {
+	for _, key := range []string{"", "123", "123456789", "00011234abcd", "00011234abcd1234x"} {
+		signature, err := sign("sq7HjrUOBfKmC576ILgskD5srU870gJ7", key, "content")
+		require.NoError(t, err, key)
+		require.Len(t, signature, 32, key)
+	}
+}
+
+func TestConfirmMissingDate(t *testing.T) {
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000", "Ds_Date": ""})
+	_, err := Confirm(context.Background(), testSecret, signed)
+	require.ErrorContains(t, err, "failed to parse datetime")
+}
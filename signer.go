@@ -0,0 +1,82 @@
+package redsys
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Signer computes the signatures of the transactions: the 3DES derivation of the key from the order and the
+// HMAC-SHA256 of the content. It allows the secret to live outside of the application, e.g. in a KMS.
+type Signer interface {
+	// Sign returns the raw signature of the content with the key derived from the order.
+	Sign(ctx context.Context, order, content string) ([]byte, error)
+}
+
+// LocalSigner signs with a secret kept in memory.
+type LocalSigner struct {
+	// Secret to sign transactions assigned by the bank.
+	Secret string
+}
+
+// Sign implements Signer.
+func (signer LocalSigner) Sign(ctx context.Context, order, content string) ([]byte, error) {
+	return sign(signer.Secret, order, content)
+}
+
+// HTTPSigner signs calling a remote service that keeps the secret. The service receives a POST request with the
+// JSON body {"order": "...", "content": "..."} and should reply with the JSON body {"signature": "..."} containing
+// the signature encoded in standard base64.
+type HTTPSigner struct {
+	// URL of the signer service.
+	URL string
+
+	// HTTP client to use in the requests. By default it will be http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type signerRequest struct {
+	Order   string `json:"order"`
+	Content string `json:"content"`
+}
+
+type signerResponse struct {
+	Signature string `json:"signature"`
+}
+
+// Sign implements Signer.
+func (signer *HTTPSigner) Sign(ctx context.Context, order, content string) ([]byte, error) {
+	body, err := json.Marshal(signerRequest{Order: order, Content: content})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal signer request: %v", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, signer.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cannot prepare signer request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := httpClientOrDefault(signer.HTTPClient).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("cannot send signer request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected signer status code %d", resp.StatusCode)
+	}
+
+	var reply signerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("cannot decode signer response: %v", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(reply.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode signer signature: %v", err)
+	}
+	if len(signature) != 32 {
+		return nil, fmt.Errorf("invalid signer signature length %d", len(signature))
+	}
+	return signature, nil
+}
@@ -0,0 +1,88 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newSignerServer emulates a remote signer service that keeps the test secret.
+func newSignerServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, r.Method, http.MethodPost)
+		var req signerRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if req.Order == "" {
+			http.Error(w, "missing order", http.StatusBadRequest)
+			return
+		}
+		signature, err := sign(testSecret, req.Order, req.Content)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(w).Encode(signerResponse{
+			Signature: base64.StdEncoding.EncodeToString(signature),
+		}))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLocalSigner(t *testing.T) {
+	signer := LocalSigner{Secret: testSecret}
+	signature, err := signer.Sign(context.Background(), "00011234abcd", "foo")
+	require.NoError(t, err)
+
+	expected, err := sign(testSecret, "00011234abcd", "foo")
+	require.NoError(t, err)
+	require.Equal(t, signature, expected)
+}
+
+func TestHTTPSigner(t *testing.T) {
+	server := newSignerServer(t)
+	signer := &HTTPSigner{URL: server.URL, HTTPClient: server.Client()}
+
+	signature, err := signer.Sign(context.Background(), "00011234abcd", "foo")
+	require.NoError(t, err)
+
+	expected, err := sign(testSecret, "00011234abcd", "foo")
+	require.NoError(t, err)
+	require.Equal(t, signature, expected)
+}
+
+func TestHTTPSignerError(t *testing.T) {
+	server := newSignerServer(t)
+	signer := &HTTPSigner{URL: server.URL, HTTPClient: server.Client()}
+
+	_, err := signer.Sign(context.Background(), "", "foo")
+	require.EqualError(t, err, "unexpected signer status code 400")
+}
+
+func TestSignWithSigner(t *testing.T) {
+	server := newSignerServer(t)
+	session := Session{Order: "00011234abcd", Amount: 1000}
+
+	remote, err := Sign(context.Background(), Merchant{Signer: &HTTPSigner{URL: server.URL, HTTPClient: server.Client()}}, session)
+	require.NoError(t, err)
+	local, err := Sign(context.Background(), Merchant{Secret: testSecret}, session)
+	require.NoError(t, err)
+
+	require.Equal(t, remote, local)
+}
+
+func TestConfirmKeysWithSigner(t *testing.T) {
+	server := newSignerServer(t)
+	keys := KeySet{
+		{Version: "remote", Signer: &HTTPSigner{URL: server.URL, HTTPClient: server.Client()}},
+	}
+	signed := signNotification(t, map[string]string{"Ds_Order": "00011234abcd", "Ds_Response": "0000"})
+
+	operation, err := ConfirmKeys(context.Background(), keys, signed)
+	require.NoError(t, err)
+	require.Equal(t, operation.Status, StatusApproved)
+	require.Equal(t, operation.KeyVersion, "remote")
+}
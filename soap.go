@@ -0,0 +1,223 @@
+package redsys
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const pathSOAP = "/sis/services/SerClsWSEntradaV2"
+
+// SOAPClient sends server-to-server operations to the legacy SOAP webservice of the bank. Use it only when the
+// bank has not enabled the REST endpoint for the merchant.
+type SOAPClient struct {
+	// HTTP client to use in the requests. By default it will be http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Base URL of the bank. By default it will be the production or debug server depending on the merchant.
+	BaseURL string
+}
+
+type datosEntrada struct {
+	XMLName         xml.Name        `xml:"DATOSENTRADA"`
+	Amount          int32           `xml:"DS_MERCHANT_AMOUNT"`
+	Order           string          `xml:"DS_MERCHANT_ORDER"`
+	MerchantCode    string          `xml:"DS_MERCHANT_MERCHANTCODE"`
+	Currency        Currency        `xml:"DS_MERCHANT_CURRENCY"`
+	TransactionType TransactionType `xml:"DS_MERCHANT_TRANSACTIONTYPE"`
+	Terminal        int64           `xml:"DS_MERCHANT_TERMINAL"`
+	Data            string          `xml:"DS_MERCHANT_MERCHANTDATA,omitempty"`
+	Identifier      string          `xml:"DS_MERCHANT_IDENTIFIER,omitempty"`
+	DirectPayment   string          `xml:"DS_MERCHANT_DIRECTPAYMENT,omitempty"`
+	Pan             string          `xml:"DS_MERCHANT_PAN,omitempty"`
+	ExpiryDate      string          `xml:"DS_MERCHANT_EXPIRYDATE,omitempty"`
+	CVV2            string          `xml:"DS_MERCHANT_CVV2,omitempty"`
+	COFIni          string          `xml:"DS_MERCHANT_COF_INI,omitempty"`
+	COFType         COFType         `xml:"DS_MERCHANT_COF_TYPE,omitempty"`
+	COFTxnID        string          `xml:"DS_MERCHANT_COF_TXNID,omitempty"`
+	ExcepSCA        string          `xml:"DS_MERCHANT_EXCEP_SCA,omitempty"`
+}
+
+type soapEnvelope struct {
+	Body struct {
+		Response struct {
+			Return string `xml:"trataPeticionReturn"`
+		} `xml:"trataPeticionResponse"`
+	} `xml:"Body"`
+}
+
+type retornoXML struct {
+	Code      string        `xml:"CODIGO"`
+	Operation *operacionXML `xml:"OPERACION"`
+}
+
+type operacionXML struct {
+	Amount          string `xml:"Ds_Amount"`
+	Currency        string `xml:"Ds_Currency"`
+	Order           string `xml:"Ds_Order"`
+	Signature       string `xml:"Ds_Signature"`
+	MerchantCode    string `xml:"Ds_MerchantCode"`
+	Terminal        string `xml:"Ds_Terminal"`
+	Response        string `xml:"Ds_Response"`
+	AuthCode        string `xml:"Ds_AuthorisationCode"`
+	TransactionType string `xml:"Ds_TransactionType"`
+	SecurePayment   string `xml:"Ds_SecurePayment"`
+	MerchantData    string `xml:"Ds_MerchantData"`
+	CardCountry     string `xml:"Ds_Card_Country"`
+	CardNumber      string `xml:"Ds_CardNumber"`
+	CardType        string `xml:"Ds_Card_Type"`
+	Identifier      string `xml:"Ds_Merchant_Identifier"`
+	ExpiryDate      string `xml:"Ds_ExpiryDate"`
+	COFTxnID        string `xml:"Ds_Merchant_Cof_Txnid"`
+}
+
+// Send signs the request with the merchant data, sends it to the webservice and verifies the signed response. If
+// the bank rejects the request itself a *SISError will be returned.
+//
+// The webservice does not accept EMV3DS data nor InSite operations; use the REST client for them.
+func (client *SOAPClient) Send(ctx context.Context, merchant Merchant, req Request) (Operation, error) {
+	if !reOrder.MatchString(req.Order) {
+		return Operation{}, fmt.Errorf("invalid order format %q", req.Order)
+	}
+	if req.Cardholder != nil || req.IDOper != "" {
+		return Operation{}, fmt.Errorf("EMV3DS and InSite operations are not supported by the SOAP webservice")
+	}
+	currency, err := merchant.currency()
+	if err != nil {
+		return Operation{}, err
+	}
+	cof, err := req.CardOnFile.request()
+	if err != nil {
+		return Operation{}, err
+	}
+
+	input := datosEntrada{
+		Amount:          req.Amount,
+		Order:           req.Order,
+		MerchantCode:    merchant.Code,
+		Currency:        currency,
+		TransactionType: req.TransactionType,
+		Terminal:        merchant.Terminal,
+		Data:            req.Data,
+		Identifier:      req.Identifier,
+		Pan:             req.Pan,
+		ExpiryDate:      req.ExpiryDate,
+		CVV2:            req.CVV2,
+	}
+	if req.DirectPayment {
+		input.DirectPayment = "true"
+	}
+	if cof != nil {
+		input.COFIni = cof.Ini
+		input.COFType = cof.Type
+		input.COFTxnID = cof.TxnID
+		input.ExcepSCA = cof.ExcepSCA
+	}
+	inputXML, err := xml.Marshal(input)
+	if err != nil {
+		return Operation{}, fmt.Errorf("cannot marshal params: %v", err)
+	}
+	signature, err := merchant.signer().Sign(ctx, req.Order, string(inputXML))
+	if err != nil {
+		return Operation{}, fmt.Errorf("%v", err)
+	}
+	request := fmt.Sprintf("<REQUEST>%s<DS_SIGNATUREVERSION>HMAC_SHA256_V1</DS_SIGNATUREVERSION><DS_SIGNATURE>%s</DS_SIGNATURE></REQUEST>", inputXML, base64.StdEncoding.EncodeToString(signature))
+
+	var body bytes.Buffer
+	body.WriteString(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:web="http://webservice.sis.sermepa.es">`)
+	body.WriteString(`<soapenv:Header/><soapenv:Body><web:trataPeticion><web:datoEntrada>`)
+	if err := xml.EscapeText(&body, []byte(request)); err != nil {
+		return Operation{}, fmt.Errorf("cannot escape request: %v", err)
+	}
+	body.WriteString(`</web:datoEntrada></web:trataPeticion></soapenv:Body></soapenv:Envelope>`)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURLOrDefault(client.BaseURL, merchant)+pathSOAP, &body)
+	if err != nil {
+		return Operation{}, fmt.Errorf("cannot prepare request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	httpReq.Header.Set("SOAPAction", "")
+	resp, err := httpClientOrDefault(client.HTTPClient).Do(httpReq)
+	if err != nil {
+		return Operation{}, fmt.Errorf("cannot send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Operation{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var envelope soapEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return Operation{}, fmt.Errorf("cannot decode response: %v", err)
+	}
+	var reply retornoXML
+	if err := xml.Unmarshal([]byte(envelope.Body.Response.Return), &reply); err != nil {
+		return Operation{}, fmt.Errorf("cannot decode response: %v", err)
+	}
+	if reply.Code != "0" {
+		return Operation{}, &SISError{Code: reply.Code}
+	}
+	if reply.Operation == nil {
+		return Operation{}, fmt.Errorf("missing operation in the response of order %q", req.Order)
+	}
+
+	params, err := reply.Operation.verify(ctx, merchant.keys(), req.Order)
+	if err != nil {
+		return Operation{}, err
+	}
+	return newServerOperation(params)
+}
+
+// verify checks the signature of the response and returns the parsed parameters. The webservice signs the
+// concatenation of some of the fields instead of the whole XML. The response must belong to the requested order.
+func (op *operacionXML) verify(ctx context.Context, keys KeySet, order string) (Params, error) {
+	content := strings.Join([]string{
+		op.Amount,
+		op.Order,
+		op.MerchantCode,
+		op.Currency,
+		op.Response,
+		op.CardNumber,
+		op.TransactionType,
+		op.SecurePayment,
+	}, "")
+	decodedSignature, err := decodeBase64(op.Signature)
+	if err != nil {
+		return Params{}, fmt.Errorf("cannot decode signature: %v", err)
+	}
+	_, ok, err := matchKey(ctx, keys, op.Order, content, decodedSignature)
+	if err != nil {
+		return Params{}, err
+	}
+	if !ok {
+		return Params{}, fmt.Errorf("bad signature %q", op.Signature)
+	}
+	if op.Order != order {
+		return Params{}, fmt.Errorf("unexpected order %q in the response of order %q", op.Order, order)
+	}
+
+	params := Params{
+		Order:              op.Order,
+		MerchantCode:       op.MerchantCode,
+		RawTerminal:        op.Terminal,
+		RawResponse:        op.Response,
+		RawAmount:          op.Amount,
+		RawCurrency:        op.Currency,
+		RawTransactionType: op.TransactionType,
+		AuthCode:           op.AuthCode,
+		Country:            op.CardCountry,
+		CardType:           op.CardType,
+		Data:               op.MerchantData,
+		Identifier:         op.Identifier,
+		ExpiryDate:         op.ExpiryDate,
+		COFTxnID:           op.COFTxnID,
+	}
+	if err := params.parseRaw(); err != nil {
+		return Params{}, err
+	}
+	return params, nil
+}
@@ -0,0 +1,126 @@
+package redsys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var reDatosEntrada = regexp.MustCompile(`<DATOSENTRADA>.*</DATOSENTRADA>`)
+
+// newSOAPServer emulates the SOAP webservice of the bank. It verifies the signature of the request and returns
+// the RETORNOXML built by the reply function.
+func newSOAPServer(t *testing.T, reply func(input datosEntrada) string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, r.URL.Path, "/sis/services/SerClsWSEntradaV2")
+
+		var envelope struct {
+			Body struct {
+				Request struct {
+					Input string `xml:"datoEntrada"`
+				} `xml:"trataPeticion"`
+			} `xml:"Body"`
+		}
+		require.NoError(t, xml.NewDecoder(r.Body).Decode(&envelope))
+		request := envelope.Body.Request.Input
+
+		inputXML := reDatosEntrada.FindString(request)
+		var input datosEntrada
+		require.NoError(t, xml.Unmarshal([]byte(inputXML), &input))
+		signature, err := sign(testSecret, input.Order, inputXML)
+		require.NoError(t, err)
+		require.Contains(t, request, fmt.Sprintf("<DS_SIGNATURE>%s</DS_SIGNATURE>", base64.StdEncoding.EncodeToString(signature)))
+
+		var escaped strings.Builder
+		require.NoError(t, xml.EscapeText(&escaped, []byte(reply(input))))
+		fmt.Fprintf(w, `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><p259:trataPeticionResponse xmlns:p259="http://webservice.sis.sermepa.es"><trataPeticionReturn>%s</trataPeticionReturn></p259:trataPeticionResponse></soapenv:Body></soapenv:Envelope>`, escaped.String())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func signSOAPResponse(t *testing.T, amount, order, merchantCode, currency, response, transactionType string) string {
+	signature, err := sign(testSecret, order, amount+order+merchantCode+currency+response+transactionType+"0")
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+func TestSOAPClientSend(t *testing.T) {
+	server := newSOAPServer(t, func(input datosEntrada) string {
+		require.Equal(t, input.MerchantCode, "123456789")
+		require.EqualValues(t, input.Terminal, 1)
+		require.EqualValues(t, input.Amount, 500)
+		require.Equal(t, input.TransactionType, TransactionTypeRefund)
+		require.Equal(t, input.Currency, CurrencyEuros)
+
+		signature := signSOAPResponse(t, "500", "00011234abcd", "123456789", "978", "0900", "3")
+		return `<RETORNOXML><CODIGO>0</CODIGO><OPERACION><Ds_Amount>500</Ds_Amount><Ds_Currency>978</Ds_Currency><Ds_Order>00011234abcd</Ds_Order><Ds_Signature>` + signature + `</Ds_Signature><Ds_MerchantCode>123456789</Ds_MerchantCode><Ds_Terminal>1</Ds_Terminal><Ds_Response>0900</Ds_Response><Ds_AuthorisationCode>123456</Ds_AuthorisationCode><Ds_TransactionType>3</Ds_TransactionType><Ds_SecurePayment>0</Ds_SecurePayment><Ds_MerchantData></Ds_MerchantData><Ds_Card_Country>724</Ds_Card_Country></OPERACION></RETORNOXML>`
+	})
+	client := &SOAPClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	operation, err := client.Send(context.Background(), testMerchant(), Request{
+		TransactionType: TransactionTypeRefund,
+		Order:           "00011234abcd",
+		Amount:          500,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, operation.Status, StatusApproved)
+	require.EqualValues(t, operation.ResponseCode, 900)
+	require.EqualValues(t, operation.Params.Amount, 500)
+	require.Equal(t, operation.Params.AuthCode, "123456")
+	require.Equal(t, operation.Params.Country, "724")
+}
+
+func TestSOAPClientSendSISError(t *testing.T) {
+	server := newSOAPServer(t, func(input datosEntrada) string {
+		return `<RETORNOXML><CODIGO>SIS0051</CODIGO><RECIBIDO>...</RECIBIDO></RETORNOXML>`
+	})
+	client := &SOAPClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	_, err := client.Send(context.Background(), testMerchant(), Request{Order: "00011234abcd", Amount: 500})
+	var sisErr *SISError
+	require.True(t, errors.As(err, &sisErr))
+	require.Equal(t, sisErr.Code, "SIS0051")
+}
+
+func TestSOAPClientSendBadSignature(t *testing.T) {
+	server := newSOAPServer(t, func(input datosEntrada) string {
+		signature := signSOAPResponse(t, "500", "00011234abcd", "123456789", "978", "0000", "0")
+		return `<RETORNOXML><CODIGO>0</CODIGO><OPERACION><Ds_Amount>50000</Ds_Amount><Ds_Currency>978</Ds_Currency><Ds_Order>00011234abcd</Ds_Order><Ds_Signature>` + signature + `</Ds_Signature><Ds_MerchantCode>123456789</Ds_MerchantCode><Ds_Response>0000</Ds_Response><Ds_TransactionType>0</Ds_TransactionType><Ds_SecurePayment>0</Ds_SecurePayment></OPERACION></RETORNOXML>`
+	})
+	client := &SOAPClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	_, err := client.Send(context.Background(), testMerchant(), Request{Order: "00011234abcd", Amount: 500})
+	require.ErrorContains(t, err, "bad signature")
+}
+
+func TestSOAPClientSendUnexpectedOrder(t *testing.T) {
+	server := newSOAPServer(t, func(input datosEntrada) string {
+		signature := signSOAPResponse(t, "500", "00019999abcd", "123456789", "978", "0000", "0")
+		return `<RETORNOXML><CODIGO>0</CODIGO><OPERACION><Ds_Amount>500</Ds_Amount><Ds_Currency>978</Ds_Currency><Ds_Order>00019999abcd</Ds_Order><Ds_Signature>` + signature + `</Ds_Signature><Ds_MerchantCode>123456789</Ds_MerchantCode><Ds_Response>0000</Ds_Response><Ds_TransactionType>0</Ds_TransactionType><Ds_SecurePayment>0</Ds_SecurePayment></OPERACION></RETORNOXML>`
+	})
+	client := &SOAPClient{HTTPClient: server.Client(), BaseURL: server.URL}
+
+	_, err := client.Send(context.Background(), testMerchant(), Request{Order: "00011234abcd", Amount: 500})
+	require.EqualError(t, err, `unexpected order "00019999abcd" in the response of order "00011234abcd"`)
+}
+
+func TestSOAPClientSendEMV3DS(t *testing.T) {
+	client := new(SOAPClient)
+	_, err := client.Send(context.Background(), testMerchant(), Request{
+		Order:      "00011234abcd",
+		Amount:     500,
+		Cardholder: &Cardholder{Email: "john@example.com"},
+	})
+	require.EqualError(t, err, "EMV3DS and InSite operations are not supported by the SOAP webservice")
+}
@@ -0,0 +1,69 @@
+package redsys
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultStoreTTL is the time a MemoryStore remembers a key. The bank stops retrying the notifications long before.
+const DefaultStoreTTL = 24 * time.Hour
+
+// Store remembers the notifications already processed to run the callbacks only once for each of them.
+type Store interface {
+	// Claim marks the key as processed. It returns false if the key was already claimed before.
+	Claim(ctx context.Context, key string) (bool, error)
+
+	// Release forgets a claimed key so the notification can be processed again.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryStore is a Store that keeps the keys in memory. It is only useful for a single instance of the
+// application; the keys are lost when it restarts. Keys are forgotten after the TTL to keep the memory bounded.
+type MemoryStore struct {
+	// Time to remember each key. By default it will be DefaultStoreTTL if empty.
+	TTL time.Duration
+
+	mu   sync.Mutex
+	keys map[string]time.Time
+	now  func() time.Time
+}
+
+// Claim implements Store.
+func (store *MemoryStore) Claim(ctx context.Context, key string) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now
+	if store.now != nil {
+		now = store.now
+	}
+	ttl := store.TTL
+	if ttl == 0 {
+		ttl = DefaultStoreTTL
+	}
+	current := now()
+	for k, expires := range store.keys {
+		if !current.Before(expires) {
+			delete(store.keys, k)
+		}
+	}
+
+	if _, ok := store.keys[key]; ok {
+		return false, nil
+	}
+	if store.keys == nil {
+		store.keys = make(map[string]time.Time)
+	}
+	store.keys[key] = current.Add(ttl)
+	return true, nil
+}
+
+// Release implements Store.
+func (store *MemoryStore) Release(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.keys, key)
+	return nil
+}
@@ -0,0 +1,59 @@
+package redsys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := new(MemoryStore)
+
+	claimed, err := store.Claim(ctx, "foo")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	claimed, err = store.Claim(ctx, "foo")
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	claimed, err = store.Claim(ctx, "bar")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	require.NoError(t, store.Release(ctx, "foo"))
+	claimed, err = store.Claim(ctx, "foo")
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	store := &MemoryStore{
+		TTL: time.Hour,
+		now: func() time.Time { return now },
+	}
+
+	claimed, err := store.Claim(ctx, "foo")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	now = now.Add(59 * time.Minute)
+	claimed, err = store.Claim(ctx, "foo")
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	now = now.Add(time.Minute)
+	claimed, err = store.Claim(ctx, "bar")
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.Len(t, store.keys, 1)
+
+	claimed, err = store.Claim(ctx, "foo")
+	require.NoError(t, err)
+	require.True(t, claimed)
+}